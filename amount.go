@@ -0,0 +1,176 @@
+/*
+amount.go - amounts of money for the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+MinimumAmount is the smallest amount that Mollie accepts for an iDEAL
+transaction. Change it if you negotiated a different limit with Mollie.
+*/
+var MinimumAmount Amount = 118
+
+/*
+Amount is an amount of money in cents.
+*/
+type Amount int
+
+/*
+MaximumAmount is the largest amount that Fetch accepts. It is the largest
+value of a 32 bit int, so the package behaves the same on 32 and 64 bit
+platforms.
+*/
+const MaximumAmount Amount = math.MaxInt32
+
+/*
+AmountFromEuros converts euros to an Amount. The value is rounded to the
+nearest cent, with halves rounded away from zero, so 19.99 becomes exactly
+1999 cents. Use ParseEuros when euros can be out of range.
+*/
+func AmountFromEuros(euros float64) Amount {
+	return Amount(math.Round(euros * 100))
+}
+
+/*
+Validate returns an error when the amount is negative, less than
+MinimumAmount or more than MaximumAmount. Amounts that are too low return an
+error for which errors.Is(err, ErrAmountTooLow) is true.
+*/
+func (a Amount) Validate() error {
+	if a < 0 {
+		return fmt.Errorf("amount %d is negative", a)
+	}
+	if a < MinimumAmount {
+		return fmt.Errorf("amount %d: %w", a, ErrAmountTooLow)
+	}
+	if a > MaximumAmount {
+		return fmt.Errorf("amount %d is larger than %d", a, MaximumAmount)
+	}
+	return nil
+}
+
+/*
+ParseEuros is like AmountFromEuros, but it returns an error when euros isn't
+a number or the amount would be larger than MaximumAmount or smaller than
+-MaximumAmount, instead of overflowing.
+*/
+func ParseEuros(euros float64) (Amount, error) {
+	cents := math.Round(euros * 100)
+	if math.IsNaN(cents) || cents > float64(MaximumAmount) || cents < -float64(MaximumAmount) {
+		return 0, fmt.Errorf("amount %v euros is out of range", euros)
+	}
+	return Amount(cents), nil
+}
+
+/*
+Cents returns the amount in cents.
+*/
+func (a Amount) Cents() int {
+	return int(a)
+}
+
+/*
+Euros returns the amount in euros.
+*/
+func (a Amount) Euros() float64 {
+	return float64(a) / 100
+}
+
+/*
+String returns the amount in cents, as it is sent to Mollie.
+*/
+func (a Amount) String() string {
+	return strconv.FormatInt(int64(a), 10)
+}
+
+/*
+Currency is an ISO 4217 currency code, like "EUR".
+*/
+type Currency string
+
+/*
+DefaultCurrency is the currency of a FetchRequest without a Currency.
+*/
+const DefaultCurrency Currency = "EUR"
+
+/*
+SupportedCurrencies are the currencies that Mollie supports.
+*/
+var SupportedCurrencies = []Currency{
+	"AED", "AUD", "BGN", "BRL", "CAD", "CHF", "CZK", "DKK", "EUR", "GBP",
+	"HKD", "HUF", "ILS", "ISK", "JPY", "MXN", "MYR", "NOK", "NZD", "PHP",
+	"PLN", "RON", "RUB", "SEK", "SGD", "THB", "TWD", "USD", "ZAR",
+}
+
+/*
+Validate returns an error when the currency is not one of
+SupportedCurrencies.
+*/
+func (c Currency) Validate() error {
+	for _, supported := range SupportedCurrencies {
+		if c == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("currency %q is not supported", string(c))
+}
+
+/*
+currencySymbols maps currency codes to the symbols used by formatAmount.
+*/
+var currencySymbols = map[string]string{
+	"EUR": "€",
+	"USD": "$",
+	"GBP": "£",
+}
+
+/*
+formatAmount formats cents in currency using Dutch conventions, for example
+"€ 1.234,56". Currencies without a known symbol are shown by their code.
+*/
+func formatAmount(cents int, currency string) string {
+	if currency == "" {
+		currency = string(DefaultCurrency)
+	}
+	symbol, ok := currencySymbols[currency]
+	if !ok {
+		symbol = currency
+	}
+
+	sign := ""
+	c := int64(cents)
+	if c < 0 {
+		sign = "-"
+		c = -c
+	}
+	units := strconv.FormatInt(c/100, 10)
+	var b strings.Builder
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(digit)
+	}
+	frac := strconv.FormatInt(c%100+100, 10)[1:]
+	return symbol + " " + sign + b.String() + "," + frac
+}
@@ -0,0 +1,82 @@
+package mollie_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestParseEuros(t *testing.T) {
+	tests := []struct {
+		euros   float64
+		want    mollie.Amount
+		wantErr bool
+	}{
+		{19.99, 1999, false},
+		{21474836.47, mollie.MaximumAmount, false},
+		{21474836.48, 0, true},
+		{-21474836.48, 0, true},
+		{1e20, 0, true},
+		{math.NaN(), 0, true},
+		{math.Inf(1), 0, true},
+	}
+	for _, tt := range tests {
+		got, err := mollie.ParseEuros(tt.euros)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEuros(%v) error = %v, wantErr %v", tt.euros, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseEuros(%v) = %d, want %d", tt.euros, got, tt.want)
+		}
+	}
+}
+
+func TestFetchLargeAmount(t *testing.T) {
+	// tooLarge is a variable, so the test also compiles where int is 32 bit.
+	tooLarge := int64(mollie.MaximumAmount) + 1
+	tests := []struct {
+		amount  mollie.Amount
+		wantErr bool
+	}{
+		{mollie.MaximumAmount, false},
+		{mollie.Amount(tooLarge), true},
+	}
+	s := mollietest.NewServer()
+	defer s.Close()
+	for _, tt := range tests {
+		request := newFetchRequest()
+		request.Amount = tt.amount
+		u, err := s.Mollie.FetchURL(request)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FetchURL(amount %d) error = %v, wantErr %v", tt.amount, err, tt.wantErr)
+			continue
+		}
+		if err == nil && u.Query().Get("amount") != "2147483647" {
+			t.Errorf("amount = %q, want 2147483647", u.Query().Get("amount"))
+		}
+	}
+}
+
+func TestLargePartnerId(t *testing.T) {
+	tooLarge := int64(math.MaxInt32) + 1
+	tests := []struct {
+		partnerId int
+		wantErr   bool
+	}{
+		{math.MaxInt32, false},
+		{int(tooLarge), true},
+	}
+	for _, tt := range tests {
+		m, err := mollie.NewMollieWithOptions(tt.partnerId)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewMollieWithOptions(%d) error = %v, wantErr %v", tt.partnerId, err, tt.wantErr)
+			continue
+		}
+		if err == nil && m.CheckURL("id").Query().Get("partnerid") != "2147483647" {
+			t.Errorf("partnerid = %q, want 2147483647", m.CheckURL("id").Query().Get("partnerid"))
+		}
+	}
+}
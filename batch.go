@@ -0,0 +1,144 @@
+/*
+batch.go - check many Mollie iDEAL transactions at once
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+DefaultCheckConcurrency is the number of concurrent Check calls of CheckMany,
+unless it is changed with SetCheckConcurrency.
+*/
+const DefaultCheckConcurrency = 4
+
+/*
+SetCheckConcurrency sets the maximum number of concurrent Check calls of
+CheckMany. When n is zero or less, DefaultCheckConcurrency is used.
+*/
+func (mollie *Mollie) SetCheckConcurrency(n int) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.checkConcurrency = n
+}
+
+/*
+CheckMany checks the transactions concurrently. It returns the responses and
+the errors by transaction id. A failed Check doesn't stop the other checks, so
+the responses contain every transaction that was checked successfully.
+
+When ctx is done, CheckMany stops starting new checks and returns the results
+so far together with ctx.Err(). The transactions that were not started are in
+neither map.
+*/
+func (mollie *Mollie) CheckMany(ctx context.Context, transactionIds []string) (map[string]*MollieResponse, map[string]error, error) {
+	mollie.mu.RLock()
+	workers := mollie.checkConcurrency
+	mollie.mu.RUnlock()
+	if workers <= 0 {
+		workers = DefaultCheckConcurrency
+	}
+
+	results := make(map[string]*MollieResponse)
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	ids := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				if ctx.Err() != nil {
+					// select in the feed loop may still pick an id after ctx is done.
+					continue
+				}
+				resp, err := mollie.CheckContext(ctx, id)
+				mu.Lock()
+				if err != nil {
+					errs[id] = err
+				} else {
+					results[id] = resp
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	seen := make(map[string]bool)
+	var err error
+feed:
+	for _, id := range transactionIds {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		select {
+		case ids <- id:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(ids)
+	wg.Wait()
+
+	return results, errs, err
+}
+
+/*
+Reconciliation is a summary of the transactions checked by ReconcileFromIDs.
+*/
+type Reconciliation struct {
+	// Checked is the number of transactions that were checked successfully.
+	Checked int
+	// ByStatus is the number of checked transactions by status.
+	ByStatus map[OrderStatus]int
+	// Paid is the number of transactions for which IsPaid is true.
+	Paid int
+	// PaidAmount is the total amount in cents of the paid transactions.
+	PaidAmount Amount
+	// Errors contains the errors by transaction id of the failed checks.
+	Errors map[string]error
+}
+
+/*
+ReconcileFromIDs checks the transactions with CheckMany and returns a summary
+of the results. The XML API has no way to list transactions, so the ids must
+be stored by the caller, for example when Fetch returns.
+
+Like CheckMany, it returns the summary so far together with ctx.Err() when ctx
+is done.
+*/
+func (mollie *Mollie) ReconcileFromIDs(ctx context.Context, transactionIds []string) (*Reconciliation, error) {
+	results, errs, err := mollie.CheckMany(ctx, transactionIds)
+	summary := &Reconciliation{
+		ByStatus: make(map[OrderStatus]int),
+		Errors:   errs,
+	}
+	for _, resp := range results {
+		summary.Checked++
+		summary.ByStatus[resp.Status()]++
+		if resp.IsPaid() {
+			summary.Paid++
+			summary.PaidAmount += Amount(resp.Order.Amount)
+		}
+	}
+	return summary, err
+}
@@ -0,0 +1,147 @@
+package mollie_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+const unknownOrderXML = `<?xml version="1.0"?>
+<response>
+	<item type="error">
+		<errorcode>-10</errorcode>
+		<message>This is an unknown order.</message>
+	</item>
+</response>`
+
+/*
+checkHandler answers a check with the CheckXML of the requested transaction,
+or with an unknown order error for the transactions that start with "unknown".
+*/
+func checkHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("transaction_id")
+	if strings.HasPrefix(id, "unknown") {
+		fmt.Fprint(w, unknownOrderXML)
+		return
+	}
+	fmt.Fprint(w, strings.Replace(mollietest.CheckXML, mollietest.TransactionId, id, 1))
+}
+
+func TestCheckManyConcurrency(t *testing.T) {
+	var active, peak int32
+	m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		checkHandler(w, r)
+	})
+	m.SetCheckConcurrency(3)
+
+	var ids []string
+	for i := 0; i < 12; i++ {
+		ids = append(ids, fmt.Sprintf("tr%d", i))
+	}
+	results, errs, err := m.CheckMany(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("CheckMany: %v", err)
+	}
+	if len(results) != len(ids) || len(errs) != 0 {
+		t.Errorf("results = %d, errs = %v, want %d results", len(results), errs, len(ids))
+	}
+	if p := atomic.LoadInt32(&peak); p != 3 {
+		t.Errorf("peak concurrent requests = %d, want 3", p)
+	}
+}
+
+func TestCheckManyErrors(t *testing.T) {
+	var requests int32
+	m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		checkHandler(w, r)
+	})
+
+	ids := []string{"tr1", "unknown1", "tr2", "tr1", "unknown2", "unknown1"}
+	results, errs, err := m.CheckMany(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("CheckMany: %v", err)
+	}
+	for _, id := range []string{"tr1", "tr2"} {
+		if resp := results[id]; resp == nil || resp.Order.TransactionId != id {
+			t.Errorf("results[%s] = %v", id, resp)
+		}
+	}
+	for _, id := range []string{"unknown1", "unknown2"} {
+		if !errors.Is(errs[id], mollie.ErrTransactionNotFound) {
+			t.Errorf("errs[%s] = %v, want ErrTransactionNotFound", id, errs[id])
+		}
+	}
+	if len(results) != 2 || len(errs) != 2 {
+		t.Errorf("results = %d, errs = %d, want 2 and 2", len(results), len(errs))
+	}
+	// Every transaction is checked once.
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Errorf("requests = %d, want 4", n)
+	}
+}
+
+func TestCheckManyCancel(t *testing.T) {
+	var mu sync.Mutex
+	started := make(map[string]bool)
+	running := make(chan struct{}, 2)
+	m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		started[r.FormValue("transaction_id")] = true
+		mu.Unlock()
+		running <- struct{}{}
+		slowHandler(w, r)
+	})
+	m.SetCheckConcurrency(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-running
+		<-running
+		cancel()
+	}()
+	ids := []string{"tr1", "tr2", "tr3", "tr4", "tr5"}
+	results, errs, err := m.CheckMany(ctx, ids)
+	if err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("results = %v, want none", results)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(started) != 2 {
+		t.Fatalf("started = %v, want 2 checks", started)
+	}
+	for _, id := range ids {
+		err, ok := errs[id]
+		if !started[id] {
+			if ok {
+				t.Errorf("errs[%s] = %v for a check that wasn't started", id, err)
+			}
+			continue
+		}
+		if err != context.Canceled {
+			t.Errorf("errs[%s] = %v, want context.Canceled", id, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+breaker.go - circuit breaker for requests to the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+ErrCircuitOpen is returned without sending a request when the circuit breaker
+is open, because the previous requests to Mollie failed.
+*/
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+/*
+CircuitState is the state of the circuit breaker.
+*/
+type CircuitState int
+
+const (
+	// CircuitClosed means that requests are sent to Mollie.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen means that requests fail with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen means that a single request is sent to Mollie to
+	// find out whether it works again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+/*
+breaker is a circuit breaker. It opens after threshold consecutive failures,
+and lets a single request through after cooldown.
+*/
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	open      bool
+	openedAt  time.Time
+	probing   bool // a request is sent in the half-open state
+}
+
+/*
+stateLocked returns the state of b. b.mu must be held.
+*/
+func (b *breaker) stateLocked() CircuitState {
+	if !b.open {
+		return CircuitClosed
+	}
+	if b.probing || time.Since(b.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return CircuitOpen
+}
+
+/*
+allow returns ErrCircuitOpen when no request may be sent right now.
+*/
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.stateLocked() {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+/*
+record records the result of a request that was allowed.
+*/
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.open = false
+		return
+	}
+	b.failures++
+	if b.open || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
+
+/*
+release is called instead of record when a request that was allowed was
+cancelled, so its result says nothing about Mollie.
+*/
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+/*
+SetCircuitBreaker enables the circuit breaker. After threshold consecutive
+requests failed with a network error or a 5xx status code, BankList, Fetch
+and Check fail right away with ErrCircuitOpen. After cooldown a single
+request is sent again: when it succeeds the breaker closes, when it fails the
+breaker stays open for another cooldown. Retries of a request count as one
+request. A threshold of zero or less disables the circuit breaker, which is
+the default.
+*/
+func (mollie *Mollie) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	if threshold <= 0 {
+		mollie.breaker = nil
+		return
+	}
+	mollie.breaker = &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+/*
+CircuitState returns the state of the circuit breaker, for example for
+metrics. It is CircuitClosed when the circuit breaker is disabled.
+*/
+func (mollie *Mollie) CircuitState() CircuitState {
+	mollie.mu.RLock()
+	b := mollie.breaker
+	mollie.mu.RUnlock()
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
@@ -0,0 +1,82 @@
+package mollie_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func slowHandler(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-r.Context().Done():
+	case <-time.After(time.Second):
+	}
+}
+
+func TestCircuitBreakerTripsOnTimeouts(t *testing.T) {
+	m := newMollie(t, slowHandler)
+	m.SetTimeout(20 * time.Millisecond)
+	m.SetCircuitBreaker(2, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		_, err := m.Check(mollietest.TransactionId)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Check %d: err = %v, want a timeout", i, err)
+		}
+	}
+	if state := m.CircuitState(); state != mollie.CircuitOpen {
+		t.Fatalf("state = %s, want %s", state, mollie.CircuitOpen)
+	}
+	start := time.Now()
+	_, err := m.Check(mollietest.TransactionId)
+	if !errors.Is(err, mollie.ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Errorf("open breaker took %s to fail", d)
+	}
+}
+
+func TestCircuitBreakerIgnoresCancel(t *testing.T) {
+	m := newMollie(t, slowHandler)
+	m.SetCircuitBreaker(1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := m.CheckContext(ctx, mollietest.TransactionId)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if state := m.CircuitState(); state != mollie.CircuitClosed {
+		t.Errorf("state = %s, want %s", state, mollie.CircuitClosed)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	s.Mollie.SetCircuitBreaker(1, 20*time.Millisecond)
+
+	s.SetNextResponse("check", http.StatusServiceUnavailable, "down")
+	if _, err := s.Mollie.Check(mollietest.TransactionId); !errors.Is(err, mollie.ErrHTTPStatus) {
+		t.Fatalf("err = %v, want ErrHTTPStatus", err)
+	}
+	if state := s.Mollie.CircuitState(); state != mollie.CircuitOpen {
+		t.Fatalf("state = %s, want %s", state, mollie.CircuitOpen)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if state := s.Mollie.CircuitState(); state != mollie.CircuitHalfOpen {
+		t.Fatalf("state = %s, want %s", state, mollie.CircuitHalfOpen)
+	}
+	if _, err := s.Mollie.Check(mollietest.TransactionId); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if state := s.Mollie.CircuitState(); state != mollie.CircuitClosed {
+		t.Errorf("state = %s, want %s", state, mollie.CircuitClosed)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+builder.go - build requests for the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import "net/url"
+
+/*
+FetchRequestBuilder builds a FetchRequest with chainable setters:
+
+	request, err := mollie.NewFetchRequest().
+		Amount(1999).
+		BankID(31).
+		Description("Order 123").
+		ReturnURL(returnurl).
+		ReportURL(reporturl).
+		Build()
+*/
+type FetchRequestBuilder struct {
+	request FetchRequest
+}
+
+/*
+NewFetchRequest starts building a FetchRequest.
+*/
+func NewFetchRequest() *FetchRequestBuilder {
+	return &FetchRequestBuilder{}
+}
+
+/*
+Amount sets the amount in cents.
+*/
+func (b *FetchRequestBuilder) Amount(amount Amount) *FetchRequestBuilder {
+	b.request.Amount = amount
+	return b
+}
+
+/*
+Currency sets the currency. Without it, Fetch uses DefaultCurrency.
+*/
+func (b *FetchRequestBuilder) Currency(currency Currency) *FetchRequestBuilder {
+	b.request.Currency = currency
+	return b
+}
+
+/*
+BankID sets the id of the bank that the consumer chose.
+*/
+func (b *FetchRequestBuilder) BankID(id int) *FetchRequestBuilder {
+	b.request.BankId = id
+	return b
+}
+
+/*
+Description sets the description that the consumer sees.
+*/
+func (b *FetchRequestBuilder) Description(description string) *FetchRequestBuilder {
+	b.request.Description = description
+	return b
+}
+
+/*
+ReportURL sets the URL that Mollie calls when the status changes.
+*/
+func (b *FetchRequestBuilder) ReportURL(u *url.URL) *FetchRequestBuilder {
+	b.request.Reporturl = u
+	return b
+}
+
+/*
+ReturnURL sets the URL that the consumer returns to after paying.
+*/
+func (b *FetchRequestBuilder) ReturnURL(u *url.URL) *FetchRequestBuilder {
+	b.request.Returnurl = u
+	return b
+}
+
+/*
+IdempotencyKey sets the idempotency key of the request.
+*/
+func (b *FetchRequestBuilder) IdempotencyKey(key string) *FetchRequestBuilder {
+	b.request.IdempotencyKey = key
+	return b
+}
+
+/*
+Metadata sets the Metadata that Check returns for the transaction.
+*/
+func (b *FetchRequestBuilder) Metadata(metadata string) *FetchRequestBuilder {
+	b.request.Metadata = metadata
+	return b
+}
+
+/*
+Locale sets the language of the payment screen, one of SupportedLocales.
+*/
+func (b *FetchRequestBuilder) Locale(locale string) *FetchRequestBuilder {
+	b.request.Locale = locale
+	return b
+}
+
+/*
+ProfileKey sets the profile key, instead of the one of the client.
+*/
+func (b *FetchRequestBuilder) ProfileKey(key string) *FetchRequestBuilder {
+	b.request.ProfileKey = key
+	return b
+}
+
+/*
+Extra adds value to the extra parameter key. It can be called multiple times,
+also for the same key.
+*/
+func (b *FetchRequestBuilder) Extra(key, value string) *FetchRequestBuilder {
+	if b.request.Extra == nil {
+		b.request.Extra = make(url.Values)
+	}
+	b.request.Extra.Add(key, value)
+	return b
+}
+
+/*
+Build returns the FetchRequest, or an error when a required field is missing
+or a field is invalid. Whether http URLs and long descriptions are allowed
+depends on the Mollie client, so those are checked by Fetch. The request
+doesn't share Extra with the builder.
+*/
+func (b *FetchRequestBuilder) Build() (*FetchRequest, error) {
+	check := b.request
+	check.Description = TruncateDescription(check.Description)
+	if err := check.validate(true); err != nil {
+		return nil, err
+	}
+	request := b.request
+	if b.request.Extra != nil {
+		request.Extra = make(url.Values, len(b.request.Extra))
+		for key, values := range b.request.Extra {
+			request.Extra[key] = append([]string(nil), values...)
+		}
+	}
+	return &request, nil
+}
@@ -0,0 +1,101 @@
+package mollie_test
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestFetchRequestBuilder(t *testing.T) {
+	reporturl, _ := url.Parse("https://example.com/report")
+	returnurl, _ := url.Parse("https://example.com/return")
+
+	request, err := mollie.NewFetchRequest().
+		Amount(1999).
+		Currency("EUR").
+		BankID(31).
+		Description("Order 123").
+		ReportURL(reporturl).
+		ReturnURL(returnurl).
+		IdempotencyKey("key").
+		Metadata("order-123").
+		Locale("en_US").
+		ProfileKey("profile").
+		Extra("foo", "1").
+		Extra("foo", "2").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	want := &mollie.FetchRequest{
+		Amount:         1999,
+		Currency:       "EUR",
+		BankId:         31,
+		Description:    "Order 123",
+		Reporturl:      reporturl,
+		Returnurl:      returnurl,
+		IdempotencyKey: "key",
+		Metadata:       "order-123",
+		Locale:         "en_US",
+		ProfileKey:     "profile",
+		Extra:          url.Values{"foo": {"1", "2"}},
+	}
+	if !reflect.DeepEqual(request, want) {
+		t.Errorf("Build() = %+v, want %+v", request, want)
+	}
+}
+
+func TestFetchRequestBuilderErrors(t *testing.T) {
+	u, _ := url.Parse("https://example.com/")
+	tests := []struct {
+		name    string
+		builder *mollie.FetchRequestBuilder
+	}{
+		{"empty", mollie.NewFetchRequest()},
+		{"no description", mollie.NewFetchRequest().Amount(1999).BankID(31).ReportURL(u).ReturnURL(u)},
+		{"reserved extra", mollie.NewFetchRequest().Amount(1999).BankID(31).Description("d").ReportURL(u).ReturnURL(u).Extra("amount", "1")},
+	}
+	for _, tt := range tests {
+		if _, err := tt.builder.Build(); err == nil {
+			t.Errorf("%s: Build() returned no error", tt.name)
+		}
+	}
+}
+
+func TestFetchRequestBuilderLongDescription(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	s.Mollie.SetTruncateDescription(true)
+	u, _ := url.Parse("https://example.com/")
+
+	request, err := mollie.NewFetchRequest().
+		Amount(1999).
+		BankID(31).
+		Description(strings.Repeat("x", mollie.MaxDescriptionLength+10)).
+		ReportURL(u).
+		ReturnURL(u).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if _, err := s.Mollie.Fetch(request); err != nil {
+		t.Errorf("Fetch: %v", err)
+	}
+}
+
+func TestFetchRequestBuilderCopiesExtra(t *testing.T) {
+	u, _ := url.Parse("https://example.com/")
+	b := mollie.NewFetchRequest().Amount(1999).BankID(31).Description("d").ReportURL(u).ReturnURL(u).Extra("k", "1")
+	request, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	b.Extra("k", "2").Extra("other", "3")
+	if want := (url.Values{"k": {"1"}}); !reflect.DeepEqual(request.Extra, want) {
+		t.Errorf("Extra = %v after changing the builder, want %v", request.Extra, want)
+	}
+}
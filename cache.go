@@ -0,0 +1,192 @@
+/*
+cache.go - cache the bank list of the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+bankListCache holds the last bank list returned by Mollie, and the fallback
+list.
+*/
+type bankListCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	resp     *BankResponse
+	expires  time.Time
+	fallback *BankResponse
+	inflight *bankListCall
+	gen      uint64 // changed when the cached list must not be stored
+}
+
+/*
+SetBankListCacheTTL enables caching of the bank list. BankList returns the
+cached list for d after it was fetched from Mollie. A zero duration disables
+the cache.
+*/
+func (mollie *Mollie) SetBankListCacheTTL(d time.Duration) {
+	c := &mollie.bankCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = d
+	c.reset()
+}
+
+/*
+InvalidateBankListCache removes the cached bank list, so the next BankList
+requests it from Mollie, for example when Mollie added a bank. The TTL is not
+changed. A request that is in flight is not stored, and BankList doesn't
+wait for it.
+*/
+func (mollie *Mollie) InvalidateBankListCache() {
+	c := &mollie.bankCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reset()
+}
+
+/*
+reset removes the cached list and forgets the request in flight, so its result
+isn't stored. c.mu must be held.
+*/
+func (c *bankListCache) reset() {
+	c.resp = nil
+	c.inflight = nil
+	c.gen++
+}
+
+/*
+bankListCall is a request for the bank list that other callers can wait for.
+resp and err are set before done is closed.
+*/
+type bankListCall struct {
+	done chan struct{}
+	resp *BankResponse
+	err  error
+}
+
+/*
+cachedBankList returns the bank list from the cache, or fetches it when the
+cache is disabled or expired. Concurrent callers wait for a single request,
+until their own ctx is done. When that request fails because its caller gave
+up, the next waiter sends a new request.
+*/
+func (mollie *Mollie) cachedBankList(ctx context.Context) (*BankResponse, error) {
+	c := &mollie.bankCache
+	for {
+		c.mu.Lock()
+		if c.ttl <= 0 {
+			c.mu.Unlock()
+			return mollie.bankList(ctx)
+		}
+		if c.resp != nil && time.Now().Before(c.expires) {
+			res := c.resp.copy()
+			c.mu.Unlock()
+			res.FromCache = true
+			return res, nil
+		}
+		if call := c.inflight; call != nil {
+			c.mu.Unlock()
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if call.err == context.Canceled || call.err == context.DeadlineExceeded {
+				continue
+			}
+			if call.resp == nil {
+				return nil, call.err
+			}
+			res := call.resp.copy()
+			res.FromCache = true
+			return res, call.err
+		}
+
+		call := &bankListCall{done: make(chan struct{})}
+		c.inflight = call
+		gen := c.gen
+		c.mu.Unlock()
+
+		resp, err := mollie.bankList(ctx)
+		c.mu.Lock()
+		if c.inflight == call {
+			c.inflight = nil
+		}
+		if err == nil && c.gen == gen {
+			c.resp = resp
+			c.expires = time.Now().Add(c.ttl)
+		}
+		c.mu.Unlock()
+		if resp != nil {
+			call.resp = resp.copy()
+		}
+		call.err = err
+		close(call.done)
+		if err != nil {
+			return resp, err
+		}
+		return resp.copy(), nil
+	}
+}
+
+/*
+SetBankListFallback sets the bank list that BankList returns when the bank
+list can't be requested from Mollie, or when Mollie returns no banks. The
+returned list has Fallback set to true. The live bank list is used whenever it
+is available. A response without banks removes the fallback.
+*/
+func (mollie *Mollie) SetBankListFallback(resp BankResponse) {
+	c := &mollie.bankCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(resp.Banks) == 0 {
+		c.fallback = nil
+		return
+	}
+	c.fallback = resp.copy()
+	c.fallback.Fallback = true
+}
+
+/*
+bankListFallback returns a copy of the fallback bank list, or nil when there
+is none.
+*/
+func (mollie *Mollie) bankListFallback() *BankResponse {
+	c := &mollie.bankCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fallback == nil {
+		return nil
+	}
+	return c.fallback.copy()
+}
+
+/*
+copy returns a copy of resp that doesn't share the Banks slice or the
+headers.
+*/
+func (resp *BankResponse) copy() *BankResponse {
+	res := *resp
+	res.Banks = append([]Bank(nil), resp.Banks...)
+	res.Header = resp.Header.Clone()
+	return &res
+}
@@ -0,0 +1,240 @@
+package mollie_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestBankListCacheSingleRequest(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		fmt.Fprint(w, mollietest.BankListXML)
+	})
+	m.SetBankListCacheTTL(time.Minute)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.BankList()
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("BankList: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+func TestBankListCacheWaiterCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		fmt.Fprint(w, mollietest.BankListXML)
+	})
+	m.SetBankListCacheTTL(time.Minute)
+
+	go m.BankList()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := m.BankListContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Errorf("waiter returned after %s", d)
+	}
+}
+
+func TestBankListCacheFromCache(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	s.Mollie.SetBankListCacheTTL(time.Minute)
+
+	first, err := s.Mollie.BankList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Banks[0].Name = "changed"
+	second, err := s.Mollie.BankList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.FromCache || !second.FromCache {
+		t.Errorf("FromCache = %t, %t, want false, true", first.FromCache, second.FromCache)
+	}
+	if second.Banks[0].Name == "changed" {
+		t.Errorf("cached bank list was changed through a returned response")
+	}
+	if n := len(s.Requests()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+func TestInvalidateBankListCache(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	s.Mollie.SetBankListCacheTTL(time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Mollie.BankList(); err != nil {
+			t.Fatalf("BankList: %v", err)
+		}
+	}
+	if n := len(s.Requests()); n != 1 {
+		t.Fatalf("requests = %d before InvalidateBankListCache, want 1", n)
+	}
+
+	s.Mollie.InvalidateBankListCache()
+	if _, err := s.Mollie.BankList(); err != nil {
+		t.Fatalf("BankList: %v", err)
+	}
+	if n := len(s.Requests()); n != 2 {
+		t.Errorf("requests = %d after InvalidateBankListCache, want 2", n)
+	}
+}
+
+func TestBankListFallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantFallback bool
+	}{
+		{"unavailable", http.StatusServiceUnavailable, "maintenance", true},
+		{"no banks", http.StatusOK, `<?xml version="1.0"?><response></response>`, true},
+		{"not found", http.StatusNotFound, "not found", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mollietest.NewServer()
+			defer s.Close()
+			s.Mollie.SetBankListFallback(mollie.BankResponse{Banks: []mollie.Bank{{Id: 31, Name: "ABN AMRO"}}})
+			s.SetResponse("banklist", tt.status, tt.body)
+
+			resp, err := s.Mollie.BankList()
+			if !tt.wantFallback {
+				if err == nil {
+					t.Fatalf("BankList returned %v, want an error", resp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BankList: %v", err)
+			}
+			if !resp.Fallback || len(resp.Banks) != 1 {
+				t.Errorf("resp = %+v, want the fallback", resp)
+			}
+		})
+	}
+}
+
+func TestBankListFallbackMollieError(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	s.Mollie.SetBankListFallback(mollie.BankResponse{Banks: []mollie.Bank{{Id: 31, Name: "ABN AMRO"}}})
+	s.SetResponse("banklist", http.StatusOK, `<?xml version="1.0"?>
+<response>
+	<item type="error">
+		<errorcode>-16</errorcode>
+		<message>This is an invalid profile key.</message>
+	</item>
+</response>`)
+
+	_, err := s.Mollie.BankList()
+	var mollieErr *mollie.MollieError
+	if !errors.As(err, &mollieErr) || mollieErr.Code != mollie.CodeInvalidProfile {
+		t.Fatalf("err = %v, want the MollieError with CodeInvalidProfile", err)
+	}
+	if !errors.Is(err, mollie.ErrInvalidProfile) {
+		t.Errorf("err = %v, want ErrInvalidProfile", err)
+	}
+}
+
+func TestCloneKeepsBankListFallback(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	s.Mollie.SetBankListFallback(mollie.BankResponse{Banks: []mollie.Bank{{Id: 31, Name: "ABN AMRO"}}})
+	s.SetResponse("banklist", http.StatusServiceUnavailable, "maintenance")
+	clone := s.Mollie.Clone()
+
+	resp, err := clone.BankList()
+	if err != nil {
+		t.Fatalf("BankList: %v", err)
+	}
+	if !resp.Fallback || len(resp.Banks) != 1 || resp.Banks[0].Id != 31 {
+		t.Errorf("resp = %+v, want the fallback", resp)
+	}
+
+	// Changing the fallback of the clone doesn't change the original.
+	clone.SetBankListFallback(mollie.BankResponse{})
+	if _, err := s.Mollie.BankList(); err != nil {
+		t.Errorf("BankList of the original: %v", err)
+	}
+}
+
+func TestInvalidateBankListCacheInFlight(t *testing.T) {
+	var requests int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			close(started)
+			<-release
+		}
+		fmt.Fprint(w, mollietest.BankListXML)
+	})
+	m.SetBankListCacheTTL(time.Minute)
+
+	done := make(chan error)
+	go func() {
+		_, err := m.BankList()
+		done <- err
+	}()
+	<-started
+	m.InvalidateBankListCache()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("BankList: %v", err)
+	}
+
+	resp, err := m.BankList()
+	if err != nil {
+		t.Fatalf("BankList: %v", err)
+	}
+	if resp.FromCache {
+		t.Errorf("FromCache = true, want the list requested after InvalidateBankListCache")
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+}
@@ -0,0 +1,404 @@
+/*
+decode.go - decode responses of the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+DecodeBankResponse decodes a bank list response of Mollie from r, in the same
+way as BankList, for example to replay a captured response in a test. It
+returns a *MollieError when the response is an error response. Unlike
+BankList it doesn't return ErrNoBanksAvailable for an empty list.
+*/
+func DecodeBankResponse(r io.Reader) (*BankResponse, error) {
+	data, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBankResponse(data, decodeOptions{})
+}
+
+/*
+DecodeMollieResponse decodes a Fetch or Check response of Mollie from r, in
+the same way as Fetch and Check. It returns a *MollieError when the response
+is an error response.
+*/
+func DecodeMollieResponse(r io.Reader) (*MollieResponse, error) {
+	data, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMollieResponse(data, decodeOptions{})
+}
+
+/*
+readAll reads r, up to DefaultMaxBodySize bytes.
+*/
+func readAll(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, DefaultMaxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > DefaultMaxBodySize {
+		return nil, fmt.Errorf("response is larger than %d bytes: %w", DefaultMaxBodySize, ErrBodyTooLarge)
+	}
+	return data, nil
+}
+
+func decodeBankResponse(data []byte, opts decodeOptions) (*BankResponse, error) {
+	res := &BankResponse{}
+	if err := decode(data, res, opts); err != nil {
+		return nil, err
+	}
+	res.RawXML = data
+	return res, nil
+}
+
+func decodeMollieResponse(data []byte, opts decodeOptions) (*MollieResponse, error) {
+	res := &MollieResponse{}
+	if err := decode(data, res, opts); err != nil {
+		return nil, err
+	}
+	res.RawXML = data
+	return res, nil
+}
+
+/*
+decodeOptions are the settings of decode.
+*/
+type decodeOptions struct {
+	strict   bool // elements that v has no field for are an error
+	maxDepth int  // maximum nesting depth, DefaultMaxXMLDepth when zero
+}
+
+/*
+decode unmarshals the XML response in data into v. When the response is an
+error response, it returns a *MollieError. A response that is nested deeper
+than the maximum depth is an error.
+*/
+func decode(data []byte, v interface{}, opts decodeOptions) error {
+	if err := checkDepth(data, opts.maxDepth); err != nil {
+		return wrap(ErrDecodeFailed, err)
+	}
+	if item := errorItem(data); item != nil {
+		return item
+	}
+	if err := newDecoder(data).Decode(v); err != nil {
+		return wrap(ErrDecodeFailed, err)
+	}
+	if opts.strict {
+		if err := checkElements(data, v); err != nil {
+			return wrap(ErrDecodeFailed, err)
+		}
+	}
+	return nil
+}
+
+/*
+errorItem returns the error in data when it is an error response, or nil.
+*/
+func errorItem(data []byte) *MollieError {
+	var fault struct {
+		Item *MollieError `xml:"item"`
+	}
+	if err := newDecoder(data).Decode(&fault); err == nil && fault.Item != nil && fault.Item.Type == "error" {
+		return fault.Item
+	}
+	return nil
+}
+
+/*
+checkDepth returns an error when the elements in data are nested deeper than
+maxDepth. It stops at the first syntax error and leaves reporting it to the
+decoder.
+*/
+func checkDepth(data []byte, maxDepth int) error {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxXMLDepth
+	}
+	d := newDecoder(data)
+	depth := 0
+	for {
+		tok, err := d.RawToken()
+		if err != nil {
+			return nil
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("response is nested deeper than %d elements", maxDepth)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+/*
+UnmarshalXML decodes a bank list. It finds the bank elements directly in the
+response, but also when Mollie wraps them in another element, like
+<banks><bank>...</bank></banks>.
+*/
+func (resp *BankResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	resp.XMLName = start.Name
+	resp.Banks = nil
+	return collectBanks(d, &resp.Banks)
+}
+
+/*
+collectBanks decodes all bank elements until the end of the current element.
+*/
+func collectBanks(d *xml.Decoder, banks *[]Bank) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Local == "bank" {
+				var bank Bank
+				if err := d.DecodeElement(&bank, &tok); err != nil {
+					return err
+				}
+				*banks = append(*banks, bank)
+			} else if err := collectBanks(d, banks); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+/*
+checkElements returns an error for the first element in data that has no
+field in v.
+*/
+func checkElements(data []byte, v interface{}) error {
+	known := make(map[string]bool)
+	t := reflect.TypeOf(v).Elem()
+	root := t.Name()
+	if f, ok := t.FieldByName("XMLName"); ok {
+		if name := tagName(f); name != "" {
+			root = name
+		}
+	}
+	known[root] = true
+	elementPaths(t, root, known)
+	if _, ok := v.(*BankResponse); ok {
+		return checkBankElements(data, root, known)
+	}
+
+	decoder := newDecoder(data)
+	var path []string
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			path = append(path, tok.Name.Local)
+			p := strings.Join(path, "/")
+			if !known[p] {
+				return fmt.Errorf("unexpected element <%s> in response", p)
+			}
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+/*
+checkBankElements is checkElements for a bank list. Like collectBanks, it
+accepts wrapper elements around the banks, but only when they contain a bank.
+The elements in a bank are checked against the known paths of a bank in the
+root element.
+*/
+func checkBankElements(data []byte, root string, known map[string]bool) error {
+	type element struct {
+		path    string
+		hasBank bool
+	}
+	decoder := newDecoder(data)
+	var stack []element
+	bank := -1 // the index of the bank element in stack
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name := tok.Name.Local
+			var p string
+			switch {
+			case len(stack) == 0:
+				p = name
+				if p != root {
+					return fmt.Errorf("unexpected element <%s> in response", p)
+				}
+			case bank >= 0:
+				p = stack[len(stack)-1].path + "/" + name
+				if !known[p] {
+					return fmt.Errorf("unexpected element <%s> in response", p)
+				}
+			case name == "bank":
+				p = root + "/bank"
+				bank = len(stack)
+				for i := range stack {
+					stack[i].hasBank = true
+				}
+			default:
+				p = stack[len(stack)-1].path + "/" + name
+			}
+			stack = append(stack, element{path: p})
+		case xml.EndElement:
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == bank {
+				bank = -1
+			} else if bank < 0 && len(stack) > 0 && !e.hasBank && !known[e.path] {
+				return fmt.Errorf("unexpected element <%s> in response", e.path)
+			}
+		}
+	}
+}
+
+/*
+elementPaths adds the paths of the elements of the fields of struct type t to
+known, prefixed with prefix.
+*/
+func elementPaths(t reflect.Type, prefix string, known map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("xml")
+		if f.PkgPath != "" || f.Name == "XMLName" || tag == "-" || strings.Contains(tag, ",") {
+			continue
+		}
+		name := tagName(f)
+		if name == "" {
+			name = f.Name
+		}
+		p := prefix
+		for _, part := range strings.Split(name, ">") {
+			p += "/" + part
+			known[p] = true
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			elementPaths(ft, p, known)
+		}
+	}
+}
+
+/*
+tagName returns the element name in the xml tag of f.
+*/
+func tagName(f reflect.StructField) string {
+	name := f.Tag.Get("xml")
+	if i := strings.Index(name, ","); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+/*
+newDecoder returns an xml.Decoder for data that also understands ISO-8859-1
+encoded responses.
+*/
+func newDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charsetReader
+	return decoder
+}
+
+/*
+charsetReader converts input in charset to UTF-8. Mollie sometimes sends
+ISO-8859-1, for example for consumer names with accents.
+*/
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "latin-1", "l1":
+		return &latin1Reader{r: bufio.NewReader(input)}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
+
+/*
+latin1Reader converts ISO-8859-1 to UTF-8. Every byte is the code point of a
+character.
+*/
+type latin1Reader struct {
+	r       *bufio.Reader
+	pending []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(l.pending) > 0 {
+			c := copy(p[n:], l.pending)
+			l.pending = l.pending[c:]
+			n += c
+			continue
+		}
+		b, err := l.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			continue
+		}
+		var buf [2]byte
+		utf8.EncodeRune(buf[:], rune(b))
+		l.pending = buf[:]
+	}
+	return n, nil
+}
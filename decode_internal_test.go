@@ -0,0 +1,31 @@
+package mollie
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLatin1Reader(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"Janssen", "Janssen"},
+		{"Hr J M\xfcller-Cr\xe9mieux", "Hr J Müller-Crémieux"},
+		{"\xe9\xe9\xe9", "ééé"},
+	}
+	for _, tt := range tests {
+		// One byte reads split every accented character over two reads.
+		r := iotest.OneByteReader(&latin1Reader{r: bufio.NewReader(strings.NewReader(tt.in))})
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll(%q): %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("latin1Reader(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
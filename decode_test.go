@@ -0,0 +1,149 @@
+package mollie_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestBankListShapes(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		banks []mollie.Bank
+	}{
+		{"single", `<?xml version="1.0"?>
+<response>
+	<bank>
+		<bank_id>0031</bank_id>
+		<bank_name>ABN AMRO</bank_name>
+	</bank>
+</response>`, []mollie.Bank{{Id: 31, Name: "ABN AMRO"}}},
+		{"list", `<?xml version="1.0"?>
+<response>
+	<bank>
+		<bank_id>0031</bank_id>
+		<bank_name>ABN AMRO</bank_name>
+	</bank>
+	<bank>
+		<bank_id>0721</bank_id>
+		<bank_name>ING</bank_name>
+	</bank>
+</response>`, []mollie.Bank{{Id: 31, Name: "ABN AMRO"}, {Id: 721, Name: "ING"}}},
+		{"wrapped", `<?xml version="1.0"?>
+<response>
+	<banks>
+		<bank>
+			<bank_id>0031</bank_id>
+			<bank_name>ABN AMRO</bank_name>
+		</bank>
+		<bank>
+			<bank_id>0721</bank_id>
+			<bank_name>ING</bank_name>
+		</bank>
+	</banks>
+</response>`, []mollie.Bank{{Id: 31, Name: "ABN AMRO"}, {Id: 721, Name: "ING"}}},
+		{"wrapped single", `<?xml version="1.0"?>
+<response>
+	<banks>
+		<bank>
+			<bank_id>0021</bank_id>
+			<bank_name>Rabobank</bank_name>
+		</bank>
+	</banks>
+</response>`, []mollie.Bank{{Id: 21, Name: "Rabobank"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mollietest.NewServer()
+			defer s.Close()
+			s.SetResponse("banklist", http.StatusOK, tt.body)
+
+			resp, err := s.Mollie.BankList()
+			if err != nil {
+				t.Fatalf("BankList: %v", err)
+			}
+			if len(resp.Banks) != len(tt.banks) {
+				t.Fatalf("banks = %v, want %v", resp.Banks, tt.banks)
+			}
+			for i, bank := range resp.Banks {
+				if bank.Id != tt.banks[i].Id || bank.Name != tt.banks[i].Name {
+					t.Errorf("bank %d = %d %q, want %d %q", i, bank.Id, bank.Name, tt.banks[i].Id, tt.banks[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestStrictBankList(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		banks   int
+		wantErr bool
+	}{
+		{"list", mollietest.BankListXML, 3, false},
+		{"wrapped", `<response><banks><bank><bank_id>0031</bank_id><bank_name>ABN AMRO</bank_name></bank>` +
+			`<bank><bank_id>0721</bank_id><bank_name>ING</bank_name></bank></banks></response>`, 2, false},
+		{"doubly wrapped", `<response><ideal><banks><bank><bank_id>0031</bank_id><bank_name>ABN AMRO</bank_name></bank></banks></ideal></response>`, 1, false},
+		{"unknown element", `<response><bank><bank_id>0031</bank_id><bank_name>ABN AMRO</bank_name></bank><extra>1</extra></response>`, 0, true},
+		{"unknown element in bank", `<response><banks><bank><bank_id>0031</bank_id><bank_country>NL</bank_country></bank></banks></response>`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mollietest.NewServer()
+			defer s.Close()
+			s.Mollie.SetStrictDecoding(true)
+			s.SetResponse("banklist", http.StatusOK, tt.body)
+
+			resp, err := s.Mollie.BankList()
+			if tt.wantErr {
+				if !errors.Is(err, mollie.ErrDecodeFailed) {
+					t.Fatalf("err = %v, want ErrDecodeFailed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BankList: %v", err)
+			}
+			if len(resp.Banks) != tt.banks {
+				t.Errorf("banks = %d, want %d", len(resp.Banks), tt.banks)
+			}
+		})
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte(mollietest.BankListXML))
+	f.Add([]byte(mollietest.FetchXML))
+	f.Add([]byte(mollietest.CheckXML))
+	f.Add([]byte(`<response><item type="error"><errorcode>-10</errorcode><message>unknown order</message></item></response>`))
+	f.Add([]byte(`<response><banks><bank><bank_id>0031</bank_id></bank></banks></response>`))
+	f.Add([]byte(strings.Repeat("<a>", 100) + strings.Repeat("</a>", 100)))
+	f.Add([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><response><order><consumer><consumerName>M\xfcller</consumerName></consumer></order></response>`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, maxDepth := range []int{0, 3} {
+			var strictErr [2]error
+			for i, strict := range []bool{false, true} {
+				_, bankErr := mollie.DecodeBankResponseOptions(data, strict, maxDepth)
+				_, mollieErr := mollie.DecodeMollieResponseOptions(data, strict, maxDepth)
+				for _, err := range []error{bankErr, mollieErr} {
+					var e *mollie.MollieError
+					if err != nil && !errors.Is(err, mollie.ErrDecodeFailed) && !errors.As(err, &e) {
+						t.Errorf("strict %v, maxDepth %d: err = %v, want ErrDecodeFailed or a *MollieError", strict, maxDepth, err)
+					}
+				}
+				strictErr[i] = mollieErr
+			}
+			// Strict decoding only rejects more responses.
+			if strictErr[0] != nil && strictErr[1] == nil {
+				t.Errorf("maxDepth %d: strict decoding accepts what normal decoding rejects: %v", maxDepth, strictErr[0])
+			}
+		}
+	})
+}
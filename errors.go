@@ -0,0 +1,220 @@
+/*
+errors.go - errors returned by the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+/*
+ErrTransport, ErrHTTPStatus and ErrDecodeFailed tell why a request to Mollie
+failed. Use errors.Is to test for them, for example to decide whether to
+retry.
+*/
+var (
+	ErrTransport    = errors.New("transport error")
+	ErrHTTPStatus   = errors.New("unexpected HTTP status")
+	ErrDecodeFailed = errors.New("decoding the response failed")
+)
+
+/*
+ErrBodyTooLarge is returned when a response is larger than the maximum body
+size.
+*/
+var ErrBodyTooLarge = errors.New("response body too large")
+
+/*
+kindError adds a kind, one of the sentinel errors, to err without changing its
+message.
+*/
+type kindError struct {
+	kind error
+	err  error
+}
+
+func wrap(kind, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+/*
+HTTPStatusError is returned when Mollie responds with another status code
+than 200. Body contains the start of the response body, which often explains
+the error.
+*/
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+/*
+maxErrorBody is the number of bytes of the response body that is kept in an
+HTTPStatusError.
+*/
+const maxErrorBody = 4096
+
+func (e *HTTPStatusError) Error() string {
+	body := e.Body
+	if len(body) > 512 {
+		// cut at the start of a rune, so the message stays valid UTF-8
+		n := 512
+		for n > 0 && !utf8.RuneStart(body[n]) {
+			n--
+		}
+		body = body[:n] + "..."
+	}
+	return fmt.Sprintf("StatusCode not 200, but %d: %s", e.StatusCode, body)
+}
+
+/*
+Is makes errors.Is(err, ErrHTTPStatus) true for an HTTPStatusError.
+*/
+func (e *HTTPStatusError) Is(target error) bool {
+	return target == ErrHTTPStatus
+}
+
+/*
+ErrAmountTooLow is returned by Fetch when the amount is less than
+MinimumAmount.
+*/
+var ErrAmountTooLow = errors.New("amount is less than the minimum amount")
+
+/*
+ErrDescriptionTooLong is returned by Fetch when the description is longer than
+MaxDescriptionLength characters.
+*/
+var ErrDescriptionTooLong = errors.New("description is too long")
+
+/*
+ErrNoBanksAvailable is returned by BankList when Mollie returned an empty bank
+list. This usually happens during an outage, so try again later.
+*/
+var ErrNoBanksAvailable = errors.New("no banks available")
+
+/*
+ErrTransactionNotFound is returned by Check when Mollie doesn't know the
+transaction. The error is a *MollieError with CodeUnknownOrder.
+*/
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+/*
+ErrTransactionMismatch is returned by Check when Mollie returned another
+transaction than the one that was requested.
+*/
+var ErrTransactionMismatch = errors.New("transaction mismatch")
+
+/*
+ErrAmountMismatch is returned by MollieResponse.VerifyAmount when the amount
+of the transaction isn't the expected amount.
+*/
+var ErrAmountMismatch = errors.New("amount mismatch")
+
+/*
+ErrInvalidIBAN is returned by Consumer.IBAN when the account of the consumer
+isn't a valid IBAN.
+*/
+var ErrInvalidIBAN = errors.New("invalid IBAN")
+
+/*
+ErrInvalidPartner, ErrUnknownBank and ErrInvalidProfile match the MollieError
+with the corresponding error code, so errors.Is can be used to branch on the
+error that Mollie returned. ErrAmountTooLow and ErrTransactionNotFound match
+the MollieError of CodeAmountTooLow and CodeUnknownOrder.
+*/
+var (
+	ErrInvalidPartner = errors.New("invalid partner id")
+	ErrUnknownBank    = errors.New("unknown bank")
+	ErrInvalidProfile = errors.New("invalid profile key")
+)
+
+/*
+ErrorCode is the code of an error returned by Mollie.
+*/
+type ErrorCode int
+
+/*
+The error codes that Mollie returns most often.
+*/
+const (
+	CodeMissingPartnerID ErrorCode = -2
+	CodeUnknownBank      ErrorCode = -6
+	CodeUnknownOrder     ErrorCode = -10
+	CodeInvalidPartnerID ErrorCode = -11
+	CodeAmountTooLow     ErrorCode = -14
+	CodeInvalidProfile   ErrorCode = -16
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	CodeMissingPartnerID: "missing partner id",
+	CodeUnknownBank:      "unknown bank",
+	CodeUnknownOrder:     "unknown order",
+	CodeInvalidPartnerID: "invalid partner id",
+	CodeAmountTooLow:     "amount too low",
+	CodeInvalidProfile:   "invalid profile key",
+}
+
+func (c ErrorCode) String() string {
+	if name, ok := errorCodeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("error code %d", int(c))
+}
+
+/*
+MollieError is the error that Mollie returns when it can't handle a request.
+Use errors.As to get the error code, or errors.Is with one of the sentinel
+errors for the common codes.
+*/
+type MollieError struct {
+	Type    string    `xml:"type,attr"`
+	Code    ErrorCode `xml:"errorcode"`
+	Message string    `xml:"message"`
+}
+
+func (e *MollieError) Error() string {
+	return fmt.Sprintf("Mollie error %d: %s", int(e.Code), e.Message)
+}
+
+/*
+Is makes errors.Is true for the sentinel error that matches the error code.
+*/
+func (e *MollieError) Is(target error) bool {
+	switch e.Code {
+	case CodeMissingPartnerID, CodeInvalidPartnerID:
+		return target == ErrInvalidPartner
+	case CodeUnknownBank:
+		return target == ErrUnknownBank
+	case CodeUnknownOrder:
+		return target == ErrTransactionNotFound
+	case CodeAmountTooLow:
+		return target == ErrAmountTooLow
+	case CodeInvalidProfile:
+		return target == ErrInvalidProfile
+	}
+	return false
+}
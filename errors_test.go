@@ -0,0 +1,36 @@
+package mollie_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/pstuifzand/go-mollie"
+)
+
+func TestHTTPStatusErrorTruncation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"short", "Service Unavailable"},
+		{"ascii", strings.Repeat("a", 1000)},
+		{"rune at the limit", strings.Repeat("a", 511) + strings.Repeat("é", 100)},
+		{"multi-byte", strings.Repeat("€", 400)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &mollie.HTTPStatusError{StatusCode: 503, Status: "503 Service Unavailable", Body: tt.body}
+			msg := err.Error()
+			if !utf8.ValidString(msg) {
+				t.Errorf("Error() is not valid UTF-8: %q", msg)
+			}
+			if len(tt.body) <= 512 && !strings.HasSuffix(msg, tt.body) {
+				t.Errorf("Error() = %q, want the full body", msg)
+			}
+			if len(tt.body) > 512 && !strings.HasSuffix(msg, "...") {
+				t.Errorf("Error() = %q, want a truncated body", msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package mollie
+
+/*
+DecodeBankResponseOptions and DecodeMollieResponseOptions expose the decoder
+with its options to the tests.
+*/
+func DecodeBankResponseOptions(data []byte, strict bool, maxDepth int) (*BankResponse, error) {
+	return decodeBankResponse(data, decodeOptions{strict: strict, maxDepth: maxDepth})
+}
+
+func DecodeMollieResponseOptions(data []byte, strict bool, maxDepth int) (*MollieResponse, error) {
+	return decodeMollieResponse(data, decodeOptions{strict: strict, maxDepth: maxDepth})
+}
@@ -0,0 +1,91 @@
+/*
+health.go - check the connection to the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+)
+
+/*
+Ping checks that Mollie can be reached, by requesting the bank list. It
+returns nil when Mollie returns a valid response, even when it contains no
+banks. When the bank list cache is enabled, Ping uses it, so repeated calls
+don't all reach Mollie. The fallback bank list is never used.
+*/
+func (mollie *Mollie) Ping(ctx context.Context) error {
+	_, err := mollie.liveBankList(ctx)
+	if errors.Is(err, ErrNoBanksAvailable) {
+		return nil
+	}
+	return err
+}
+
+/*
+Validate checks the configuration of the client, for example in a self-check
+at startup. It checks the partner id, the profile key and the base URL. The
+base URL must use https, unless it is on the loopback interface, like that of
+a mollietest.Server, or SetAllowHTTP is enabled. When probe is true and the
+configuration is valid, it also checks that Mollie can be reached with Ping.
+It returns all problems that it found, joined with errors.Join, or nil.
+*/
+func (mollie *Mollie) Validate(ctx context.Context, probe bool) error {
+	mollie.mu.RLock()
+	partnerId := mollie.partnerId
+	profileKey := mollie.profileKey
+	baseurl := mollie.baseurl
+	allowHTTP := mollie.allowHTTP
+	mollie.mu.RUnlock()
+
+	var errs []error
+	if partnerId <= 0 || int64(partnerId) > math.MaxInt32 {
+		errs = append(errs, fmt.Errorf("partnerId %d is invalid", partnerId))
+	}
+	if strings.ContainsAny(profileKey, " \t\r\n") {
+		errs = append(errs, fmt.Errorf("profile key contains whitespace"))
+	}
+	switch {
+	case baseurl == nil:
+		errs = append(errs, fmt.Errorf("base URL is not set"))
+	case !baseurl.IsAbs() || baseurl.Host == "":
+		errs = append(errs, fmt.Errorf("base URL %q is not an absolute URL", baseurl))
+	case baseurl.Scheme != "https" && !allowHTTP && !isLoopback(baseurl.Hostname()):
+		errs = append(errs, fmt.Errorf("base URL %q doesn't use https", baseurl))
+	}
+	if probe && len(errs) == 0 {
+		if err := mollie.Ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("Mollie can't be reached: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/*
+isLoopback returns true when host is localhost or a loopback address.
+*/
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
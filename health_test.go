@@ -0,0 +1,41 @@
+package mollie_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestValidateMollietest(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	if err := s.Mollie.Validate(context.Background(), true); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	tests := []struct {
+		baseurl   string
+		allowHTTP bool
+		wantErr   bool
+	}{
+		{mollie.DefaultBaseURL, false, false},
+		{"http://localhost:8080/xml/ideal", false, false},
+		{"http://[::1]:8080/xml/ideal", false, false},
+		{"http://secure.mollie.nl/xml/ideal", false, true},
+		{"http://mollie.internal/xml/ideal", true, false},
+	}
+	for _, tt := range tests {
+		m, err := mollie.NewMollieWithOptions(mollietest.PartnerId, mollie.WithBaseURL(tt.baseurl))
+		if err != nil {
+			t.Fatalf("NewMollieWithOptions: %v", err)
+		}
+		m.SetAllowHTTP(tt.allowHTTP)
+		if err := m.Validate(context.Background(), false); (err != nil) != tt.wantErr {
+			t.Errorf("Validate with %s, allowHTTP %v: err = %v, wantErr %v", tt.baseurl, tt.allowHTTP, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+iban.go - normalize and validate the IBAN of a consumer
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+IBAN returns the account of the consumer as a normalized IBAN: in upper case
+and without spaces. It returns an error for which errors.Is(err,
+ErrInvalidIBAN) is true when the account isn't a valid IBAN. Account itself
+is not changed.
+*/
+func (consumer *Consumer) IBAN() (string, error) {
+	iban := normalizeIBAN(consumer.Account)
+	if err := checkIBAN(iban); err != nil {
+		return "", err
+	}
+	return iban, nil
+}
+
+/*
+normalizeIBAN removes the spaces from iban and converts it to upper case.
+*/
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+/*
+checkIBAN checks the format and the mod-97 checksum of a normalized IBAN.
+*/
+func checkIBAN(iban string) error {
+	if len(iban) < 15 || len(iban) > 34 {
+		return wrap(ErrInvalidIBAN, fmt.Errorf("IBAN %q has an invalid length", maskAccount(iban)))
+	}
+	for i := 0; i < len(iban); i++ {
+		c := iban[i]
+		letter := c >= 'A' && c <= 'Z'
+		digit := c >= '0' && c <= '9'
+		if (i < 2 && !letter) || (i >= 2 && i < 4 && !digit) || (!letter && !digit) {
+			return wrap(ErrInvalidIBAN, fmt.Errorf("IBAN %q contains an invalid character", maskAccount(iban)))
+		}
+	}
+
+	// Move the country code and check digits to the end and read the
+	// result as a number, with A=10 to Z=35.
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+	for i := 0; i < len(rearranged); i++ {
+		c := rearranged[i]
+		if c >= 'A' {
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(c-'0')) % 97
+		}
+	}
+	if remainder != 1 {
+		return wrap(ErrInvalidIBAN, fmt.Errorf("IBAN %q has an invalid checksum", maskAccount(iban)))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package mollie_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+)
+
+func TestConsumerIBAN(t *testing.T) {
+	tests := []struct {
+		account string
+		want    string
+		wantErr bool
+	}{
+		{"NL91ABNA0417164300", "NL91ABNA0417164300", false},
+		{"nl91 abna 0417 1643 00", "NL91ABNA0417164300", false},
+		{"BE68539007547034", "BE68539007547034", false},
+		{"DE89 3704 0044 0532 0130 00", "DE89370400440532013000", false},
+		{"GB82WEST12345698765432", "GB82WEST12345698765432", false},
+		{"NL91ABNA0417164301", "", true},
+		{"NL19ABNA0417164300", "", true},
+		{"NL91ABNA041716", "", true},
+		{"NL91ABNA0417164300" + strings.Repeat("0", 20), "", true},
+		{"1291ABNA0417164300", "", true},
+		{"NLX1ABNA0417164300", "", true},
+		{"NL91ABNA-417164300", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		consumer := mollie.Consumer{Account: tt.account}
+		got, err := consumer.IBAN()
+		if tt.wantErr {
+			if !errors.Is(err, mollie.ErrInvalidIBAN) {
+				t.Errorf("IBAN(%q): err = %v, want ErrInvalidIBAN", tt.account, err)
+			} else if len(tt.account) > 8 && strings.Contains(err.Error(), tt.account[:8]) {
+				t.Errorf("IBAN(%q): error %q contains the account", tt.account, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("IBAN(%q): %v", tt.account, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("IBAN(%q) = %q, want %q", tt.account, got, tt.want)
+		}
+		if consumer.Account != tt.account {
+			t.Errorf("IBAN(%q) changed Account to %q", tt.account, consumer.Account)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+json.go - encode a FetchRequest as JSON
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+/*
+fetchRequestJSON is the JSON form of a FetchRequest, with the URLs as
+strings.
+*/
+type fetchRequestJSON struct {
+	Amount         Amount     `json:"amount"`
+	Currency       Currency   `json:"currency,omitempty"`
+	BankId         int        `json:"bank_id"`
+	Description    string     `json:"description"`
+	Reporturl      string     `json:"reporturl,omitempty"`
+	Returnurl      string     `json:"returnurl,omitempty"`
+	IdempotencyKey string     `json:"idempotency_key,omitempty"`
+	Metadata       string     `json:"metadata,omitempty"`
+	Locale         string     `json:"locale,omitempty"`
+	ProfileKey     string     `json:"profile_key,omitempty"`
+	Extra          url.Values `json:"extra,omitempty"`
+}
+
+/*
+MarshalJSON encodes the request with the URLs as strings, for example to
+store a pending Fetch in a queue.
+*/
+func (request FetchRequest) MarshalJSON() ([]byte, error) {
+	v := fetchRequestJSON{
+		Amount:         request.Amount,
+		Currency:       request.Currency,
+		BankId:         request.BankId,
+		Description:    request.Description,
+		IdempotencyKey: request.IdempotencyKey,
+		Metadata:       request.Metadata,
+		Locale:         request.Locale,
+		ProfileKey:     request.ProfileKey,
+		Extra:          request.Extra,
+	}
+	if request.Reporturl != nil {
+		v.Reporturl = request.Reporturl.String()
+	}
+	if request.Returnurl != nil {
+		v.Returnurl = request.Returnurl.String()
+	}
+	return json.Marshal(v)
+}
+
+/*
+UnmarshalJSON decodes a request that was encoded with MarshalJSON. It returns
+an error when one of the URLs isn't an absolute http or https URL. The other
+fields are validated by Fetch.
+*/
+func (request *FetchRequest) UnmarshalJSON(data []byte) error {
+	var v fetchRequestJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	reporturl, err := parseCallbackURL("reporturl", v.Reporturl)
+	if err != nil {
+		return err
+	}
+	returnurl, err := parseCallbackURL("returnurl", v.Returnurl)
+	if err != nil {
+		return err
+	}
+	*request = FetchRequest{
+		Amount:         v.Amount,
+		Currency:       v.Currency,
+		BankId:         v.BankId,
+		Description:    v.Description,
+		Reporturl:      reporturl,
+		Returnurl:      returnurl,
+		IdempotencyKey: v.IdempotencyKey,
+		Metadata:       v.Metadata,
+		Locale:         v.Locale,
+		ProfileKey:     v.ProfileKey,
+		Extra:          v.Extra,
+	}
+	return nil
+}
+
+/*
+parseCallbackURL parses the URL in field name. An empty URL is nil.
+*/
+func parseCallbackURL(name, rawurl string) (*url.URL, error) {
+	if rawurl == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := checkCallbackURL(u, true); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return u, nil
+}
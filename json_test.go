@@ -0,0 +1,86 @@
+package mollie_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+)
+
+func TestFetchRequestJSONRoundTrip(t *testing.T) {
+	reporturl, _ := url.Parse("https://example.com/report?order=1")
+	returnurl, _ := url.Parse("http://localhost:8080/return")
+	tests := []struct {
+		name    string
+		request mollie.FetchRequest
+	}{
+		{"full", mollie.FetchRequest{
+			Amount:         1999,
+			Currency:       "EUR",
+			BankId:         31,
+			Description:    "Order 1",
+			Reporturl:      reporturl,
+			Returnurl:      returnurl,
+			IdempotencyKey: "key",
+			Metadata:       "order-1",
+			Locale:         "nl_NL",
+			ProfileKey:     "profile",
+			Extra:          url.Values{"foo": {"1", "2"}},
+		}},
+		{"no URLs", mollie.FetchRequest{Amount: 1999, BankId: 31, Description: "Order 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.request)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			var got mollie.FetchRequest
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", data, err)
+			}
+			if !reflect.DeepEqual(got, tt.request) {
+				t.Errorf("round trip = %+v, want %+v", got, tt.request)
+			}
+		})
+	}
+}
+
+func TestFetchRequestJSONURLs(t *testing.T) {
+	data, err := json.Marshal(mollie.FetchRequest{Amount: 1999, Reporturl: &url.URL{Scheme: "https", Host: "example.com", Path: "/report"}})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if v["reporturl"] != "https://example.com/report" {
+		t.Errorf("reporturl = %v, want the URL as a string", v["reporturl"])
+	}
+	if _, ok := v["returnurl"]; ok {
+		t.Errorf("returnurl = %v, want it left out", v["returnurl"])
+	}
+}
+
+func TestFetchRequestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"relative reporturl", `{"amount": 1999, "reporturl": "/report"}`},
+		{"ftp returnurl", `{"amount": 1999, "returnurl": "ftp://example.com/return"}`},
+		{"unparsable reporturl", `{"amount": 1999, "reporturl": "https://exa mple.com/%zz"}`},
+		{"no host", `{"amount": 1999, "returnurl": "https:///return"}`},
+		{"not an object", `[]`},
+		{"wrong type", `{"amount": "1999"}`},
+	}
+	for _, tt := range tests {
+		var request mollie.FetchRequest
+		if err := json.Unmarshal([]byte(tt.data), &request); err == nil {
+			t.Errorf("%s: Unmarshal(%s) returned no error, got %+v", tt.name, tt.data, request)
+		}
+	}
+}
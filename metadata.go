@@ -0,0 +1,125 @@
+/*
+metadata.go - keep metadata of Mollie iDEAL transactions
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+DefaultMetadataTTL is how long the Metadata of a transaction is kept, unless
+it is changed with SetMetadataTTL. iDEAL transactions expire long before
+that.
+*/
+const DefaultMetadataTTL = 24 * time.Hour
+
+/*
+MaxMetadataEntries is the maximum number of transactions that Metadata is
+kept for. When there are more, the Metadata that expires first is forgotten.
+*/
+const MaxMetadataEntries = 10000
+
+/*
+metadataStore keeps the Metadata of FetchRequests by transaction id, because
+the iDEAL API has no parameter to send it to Mollie. It only lives in the
+memory of the process.
+*/
+type metadataStore struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	data map[string]metadataEntry
+}
+
+type metadataEntry struct {
+	metadata string
+	expires  time.Time
+}
+
+/*
+SetMetadataTTL sets how long the Metadata of a transaction is kept after
+Fetch. When d is zero or less, DefaultMetadataTTL is used. Clones share the
+Metadata, and so the TTL.
+*/
+func (mollie *Mollie) SetMetadataTTL(d time.Duration) {
+	mollie.mu.RLock()
+	store := mollie.metadata
+	mollie.mu.RUnlock()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.ttl = d
+}
+
+func (store *metadataStore) put(transactionId, metadata string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.data == nil {
+		store.data = make(map[string]metadataEntry)
+	}
+	now := time.Now()
+	if _, ok := store.data[transactionId]; !ok && len(store.data) >= MaxMetadataEntries {
+		store.evict(now)
+	}
+	ttl := store.ttl
+	if ttl <= 0 {
+		ttl = DefaultMetadataTTL
+	}
+	store.data[transactionId] = metadataEntry{metadata: metadata, expires: now.Add(ttl)}
+}
+
+/*
+evict removes the expired entries, or the entry that expires first when none
+has expired. store.mu must be held.
+*/
+func (store *metadataStore) evict(now time.Time) {
+	var first string
+	var firstExpires time.Time
+	for id, entry := range store.data {
+		if !now.Before(entry.expires) {
+			delete(store.data, id)
+			continue
+		}
+		if first == "" || entry.expires.Before(firstExpires) {
+			first, firstExpires = id, entry.expires
+		}
+	}
+	if len(store.data) >= MaxMetadataEntries {
+		delete(store.data, first)
+	}
+}
+
+/*
+get returns the metadata of the transaction, or an empty string when it
+expired. When remove is true, the metadata is forgotten.
+*/
+func (store *metadataStore) get(transactionId string, remove bool) string {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	entry, ok := store.data[transactionId]
+	if !ok {
+		return ""
+	}
+	expired := !time.Now().Before(entry.expires)
+	if remove || expired {
+		delete(store.data, transactionId)
+	}
+	if expired {
+		return ""
+	}
+	return entry.metadata
+}
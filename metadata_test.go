@@ -0,0 +1,46 @@
+package mollie
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMetadataStore(t *testing.T) {
+	store := &metadataStore{}
+	store.put("a", "order-1")
+	if got := store.get("a", false); got != "order-1" {
+		t.Errorf("get = %q, want order-1", got)
+	}
+	if got := store.get("a", true); got != "order-1" {
+		t.Errorf("get with remove = %q, want order-1", got)
+	}
+	if got := store.get("a", false); got != "" {
+		t.Errorf("get after remove = %q, want empty", got)
+	}
+}
+
+func TestMetadataStoreTTL(t *testing.T) {
+	store := &metadataStore{ttl: time.Millisecond}
+	store.put("a", "order-1")
+	time.Sleep(5 * time.Millisecond)
+	if got := store.get("a", false); got != "" {
+		t.Errorf("get after TTL = %q, want empty", got)
+	}
+	if len(store.data) != 0 {
+		t.Errorf("expired entry wasn't removed")
+	}
+}
+
+func TestMetadataStoreLimit(t *testing.T) {
+	store := &metadataStore{}
+	for i := 0; i < MaxMetadataEntries+10; i++ {
+		store.put(strconv.Itoa(i), "order")
+	}
+	if n := len(store.data); n != MaxMetadataEntries {
+		t.Errorf("entries = %d, want %d", n, MaxMetadataEntries)
+	}
+	if got := store.get(strconv.Itoa(MaxMetadataEntries+9), false); got != "order" {
+		t.Errorf("newest entry was evicted")
+	}
+}
@@ -19,82 +19,638 @@ along with this program.  If not, see <http://www.gnu.org/licenses/>.
 package mollie
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
+/*
+Mollie is a client for the Mollie iDEAL API. It is safe for concurrent use by
+multiple goroutines, including calls to the Set methods.
+*/
 type Mollie struct {
-	baseurl    *url.URL
-	partnerId  int
-	testmode   bool
-	profileKey string
+	// mu guards the settings, which can be changed by the Set methods
+	mu sync.RWMutex
+	settings
+
+	// these have their own locks
+	bankCache bankListCache
+}
+
+/*
+settings contains the configuration of a Mollie client.
+*/
+type settings struct {
+	baseurl     *url.URL
+	partnerId   int
+	testmode    bool
+	profileKey  string
+	httpClient  *http.Client
+	ownsClient  bool // httpClient was created by this package
+	warnedTLS   bool // the insecure TLS warning was logged
+	timeout     time.Duration
+	opTimeouts  map[string]time.Duration
+	maxRetries  int
+	retryDelay  time.Duration
+	retryIf     func(*http.Response, error) bool
+	limiter     *limiter
+	breaker     *breaker
+	metadata    *metadataStore // shared with clones, has its own lock
+	tracer      TraceFunc
+	metricsHook MetricsHook
+	maxBody     int64
+	maxXMLDepth int
+	logger      Logger
+	userAgent   string
+
+	requestIDFunc func() string
+
+	checkConcurrency    int
+	truncateDescription bool
+	postFetch           bool
+	postCheck           bool
+	strictDecoding      bool
+	allowHTTP           bool
+	returnParam         string
+	warnOnMismatch      bool
+
+	requestInterceptor  func(*http.Request)
+	responseInterceptor func(*http.Response)
+	redirectPolicy      func(*http.Request, []*http.Request) error
+}
+
+/*
+Client is the interface of the iDEAL API methods that Mollie implements.
+Depend on Client instead of *Mollie to replace Mollie with a fake in tests.
+*/
+type Client interface {
+	BankList() (*BankResponse, error)
+	Fetch(request *FetchRequest) (*MollieResponse, error)
+	Check(transactionId string) (*MollieResponse, error)
+}
+
+var _ Client = (*Mollie)(nil)
+
+/*
+Logger receives debug output of the requests that are sent to Mollie.
+*/
+type Logger interface {
+	Debugf(format string, args ...interface{})
 }
 
 type BankResponse struct {
-	XMLName xml.Name `xml:"response"`
-	Banks   []Bank   `xml:"bank"`
+	XMLName xml.Name `xml:"response" json:"-"`
+	Banks   []Bank   `xml:"bank" json:"banks"`
+
+	// RawXML is the body of the response as it was returned by Mollie.
+	RawXML []byte `xml:"-" json:"-"`
+
+	// Header contains the HTTP headers of the response.
+	Header http.Header `xml:"-" json:"-"`
+
+	// ClientRequestID is the id of the request that returned the response.
+	ClientRequestID string `xml:"-" json:"-"`
+
+	// FromCache is true when the response was served from the bank list
+	// cache instead of requested from Mollie.
+	FromCache bool `xml:"-" json:"-"`
+
+	// Fallback is true when the response is the fallback bank list,
+	// because the bank list couldn't be requested from Mollie.
+	Fallback bool `xml:"-" json:"-"`
 }
 
+/*
+Bank is an iDEAL bank. LogoURL and BIC are only set when Mollie includes them
+in the bank list.
+*/
 type Bank struct {
-	XMLName xml.Name `xml:"bank"`
-	Id      int      `xml:"bank_id"`
-	Name    string   `xml:"bank_name"`
+	XMLName xml.Name `xml:"bank" json:"-"`
+	Id      int      `xml:"bank_id" json:"bank_id"`
+	Name    string   `xml:"bank_name" json:"bank_name"`
+	LogoURL string   `xml:"bank_logo" json:"bank_logo,omitempty"`
+	BIC     string   `xml:"bank_bic" json:"bank_bic,omitempty"`
+}
+
+/*
+BankByID returns the bank with the given id. The second result is false when
+there is no such bank.
+*/
+func (resp *BankResponse) BankByID(id int) (*Bank, bool) {
+	for i := range resp.Banks {
+		if resp.Banks[i].Id == id {
+			return &resp.Banks[i], true
+		}
+	}
+	return nil, false
+}
+
+/*
+BankByName returns the bank with the given name, ignoring case. The second
+result is false when there is no such bank.
+*/
+func (resp *BankResponse) BankByName(name string) (*Bank, bool) {
+	for i := range resp.Banks {
+		if strings.EqualFold(resp.Banks[i].Name, name) {
+			return &resp.Banks[i], true
+		}
+	}
+	return nil, false
+}
+
+/*
+SortByName sorts the banks alphabetically by name, ignoring case.
+*/
+func (resp *BankResponse) SortByName() {
+	sort.SliceStable(resp.Banks, func(i, j int) bool {
+		return strings.ToLower(resp.Banks[i].Name) < strings.ToLower(resp.Banks[j].Name)
+	})
+}
+
+/*
+Filter returns a copy of resp that only contains the banks for which keep
+returns true.
+*/
+func (resp *BankResponse) Filter(keep func(Bank) bool) BankResponse {
+	res := *resp
+	res.Banks = nil
+	for _, bank := range resp.Banks {
+		if keep(bank) {
+			res.Banks = append(res.Banks, bank)
+		}
+	}
+	return res
+}
+
+/*
+RequestIDHeader is the HTTP header that contains the id that Mollie assigned
+to a request. Quote it when contacting Mollie support.
+*/
+const RequestIDHeader = "X-Request-Id"
+
+/*
+RequestID returns the id that Mollie assigned to the request, or an empty
+string when Mollie didn't send one.
+*/
+func (resp *BankResponse) RequestID() string {
+	return resp.Header.Get(RequestIDHeader)
 }
 
+/*
+MaxDescriptionLength is the maximum number of characters of the description of
+a transaction. Mollie truncates longer descriptions.
+*/
+const MaxDescriptionLength = 29
+
+/*
+FetchRequest contains the parameters of a new transaction.
+
+Description is shown on the bank statement of the consumer. It can be at most
+MaxDescriptionLength (29) characters long.
+
+IdempotencyKey is optional. When it is set, it is passed through as the
+idempotency_key parameter. The iDEAL API doesn't document this parameter, so
+don't count on Mollie to recognize a retried Fetch: a retry can create a
+second transaction.
+
+Currency is the currency of Amount, one of SupportedCurrencies. It defaults
+to DefaultCurrency when it is empty.
+
+Metadata is an optional reference of your own, like an order id. The iDEAL
+API has no parameter for it, so it is not sent to Mollie. Instead the Mollie
+client keeps it in the memory of the process, and returns it in the Metadata
+field of the responses of Fetch and Check for the transaction. It is
+forgotten when Check returns a completed status, after DefaultMetadataTTL or
+the TTL of SetMetadataTTL, and when the process restarts. Store your own
+reference with the transaction id when it has to survive a restart.
+
+Locale is the language of the payment pages, for example "en_US". It must be
+one of SupportedLocales. When it is empty, the pages are shown in Dutch.
+
+ProfileKey overrides the profile key of the Mollie client for this request
+only. When it is empty, the profile key of the client is used. Use it instead
+of SetProfileKey when the profile differs per request.
+
+Extra contains additional parameters for Mollie that this package doesn't
+know about yet. They are added to the parameters of the request, but can't
+replace them: Fetch returns an error when Extra contains one of the
+parameters that this package sets itself, like a, partnerid or amount.
+*/
 type FetchRequest struct {
-	Amount      int
-	BankId      int
-	Description string
-	Reporturl   *url.URL
-	Returnurl   *url.URL
+	Amount         Amount
+	Currency       Currency
+	BankId         int
+	Description    string
+	Reporturl      *url.URL
+	Returnurl      *url.URL
+	IdempotencyKey string
+	Metadata       string
+	Locale         string
+	ProfileKey     string
+	Extra          url.Values
+}
+
+/*
+SupportedLocales are the values that can be used for FetchRequest.Locale.
+*/
+var SupportedLocales = []string{
+	"nl_NL", "nl_BE", "en_US", "en_GB", "de_DE", "de_AT", "de_CH",
+	"fr_FR", "fr_BE", "es_ES",
+}
+
+func isSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+reservedParams are the parameters that are set by this package. They can't be
+used in FetchRequest.Extra.
+*/
+var reservedParams = map[string]bool{
+	"a":               true,
+	"partnerid":       true,
+	"profile_key":     true,
+	"testmode":        true,
+	"amount":          true,
+	"currency":        true,
+	"bank_id":         true,
+	"description":     true,
+	"reporturl":       true,
+	"returnurl":       true,
+	"idempotency_key": true,
+	"locale":          true,
+	"transaction_id":  true,
+}
+
+/*
+currency returns the currency of request, or DefaultCurrency when it is not
+set.
+*/
+func (request *FetchRequest) currency() Currency {
+	if request.Currency == "" {
+		return DefaultCurrency
+	}
+	return request.Currency
+}
+
+/*
+checkCallbackURL returns an error when u is not an absolute https URL, or http
+URL when allowHTTP is true.
+*/
+func checkCallbackURL(u *url.URL, allowHTTP bool) error {
+	if !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", u)
+	}
+	if u.Scheme == "https" || allowHTTP && u.Scheme == "http" {
+		return nil
+	}
+	return fmt.Errorf("%q is not an https URL", u)
+}
+
+/*
+validate returns an error naming the first field of request that is missing
+or invalid. The report and return URLs must be absolute https URLs, or http
+URLs when allowHTTP is true.
+*/
+func (request *FetchRequest) validate(allowHTTP bool) error {
+	if request == nil {
+		return fmt.Errorf("FetchRequest is nil")
+	}
+	if err := request.Amount.Validate(); err != nil {
+		return fmt.Errorf("FetchRequest: Amount: %w", err)
+	}
+	if err := request.currency().Validate(); err != nil {
+		return fmt.Errorf("FetchRequest: Currency: %w", err)
+	}
+	if request.BankId == 0 {
+		return fmt.Errorf("FetchRequest: BankId is missing")
+	}
+	if int64(request.BankId) > math.MaxInt32 || request.BankId < 0 {
+		return fmt.Errorf("FetchRequest: BankId %d is out of range", request.BankId)
+	}
+	if len(request.Description) == 0 {
+		return fmt.Errorf("FetchRequest: Description is missing")
+	}
+	if utf8.RuneCountInString(request.Description) > MaxDescriptionLength {
+		return fmt.Errorf("FetchRequest: Description: %w", ErrDescriptionTooLong)
+	}
+	if request.Reporturl == nil {
+		return fmt.Errorf("FetchRequest: Reporturl is missing")
+	}
+	if request.Returnurl == nil {
+		return fmt.Errorf("FetchRequest: Returnurl is missing")
+	}
+	if err := checkCallbackURL(request.Reporturl, allowHTTP); err != nil {
+		return fmt.Errorf("FetchRequest: Reporturl %v", err)
+	}
+	if err := checkCallbackURL(request.Returnurl, allowHTTP); err != nil {
+		return fmt.Errorf("FetchRequest: Returnurl %v", err)
+	}
+	if request.Locale != "" && !isSupportedLocale(request.Locale) {
+		return fmt.Errorf("FetchRequest: Locale %q is not supported", request.Locale)
+	}
+	for key := range request.Extra {
+		if reservedParams[key] {
+			return fmt.Errorf("FetchRequest: Extra can't contain parameter %q", key)
+		}
+	}
+	return nil
+}
+
+/*
+TruncateDescription shortens description to at most MaxDescriptionLength
+characters. It never splits a multi-byte character.
+*/
+func TruncateDescription(description string) string {
+	n := 0
+	for i := range description {
+		if n == MaxDescriptionLength {
+			return description[:i]
+		}
+		n++
+	}
+	return description
 }
 
 type MollieResponse struct {
-	XMLName xml.Name `xml:"response"`
-	Order   Order    `xml:"order"`
+	XMLName xml.Name `xml:"response" json:"-"`
+	Order   Order    `xml:"order" json:"order"`
+
+	// RawXML is the body of the response as it was returned by Mollie.
+	RawXML []byte `xml:"-" json:"-"`
+
+	// Header contains the HTTP headers of the response.
+	Header http.Header `xml:"-" json:"-"`
+
+	// ClientRequestID is the id of the request that returned the response.
+	ClientRequestID string `xml:"-" json:"-"`
+
+	// Metadata is the Metadata of the FetchRequest of the transaction.
+	Metadata string `xml:"-" json:"metadata,omitempty"`
 }
 
 type Order struct {
-	TransactionId string   `xml:"transaction_id"`
-	Amount        int      `xml:"amount"`
-	Currency      string   `xml:"currency"`
-	Payed         bool     `xml:"payed"`
-	Consumer      Consumer `xml:"consumer"`
-	URL           string
-	Message       string `xml:"message"`
-	Status        string `xml:"status"`
+	TransactionId string      `xml:"transaction_id" json:"transaction_id"`
+	Amount        int         `xml:"amount" json:"amount"`
+	Currency      string      `xml:"currency" json:"currency"`
+	Payed         bool        `xml:"payed" json:"payed"`
+	Consumer      Consumer    `xml:"consumer" json:"consumer"`
+	URL           string      `xml:"URL" json:"url"`
+	Message       string      `xml:"message" json:"message"`
+	Status        OrderStatus `xml:"status" json:"status"`
 }
 
+/*
+OrderStatus is the status of a transaction as reported by Mollie.
+
+Mollie reports Success only to the first Check after the payment. Later
+checks of the same paid transaction report CheckedBefore, so a double check
+can be told apart from the first notification.
+*/
+type OrderStatus string
+
+const (
+	StatusOpen          OrderStatus = "Open"
+	StatusSuccess       OrderStatus = "Success"
+	StatusCheckedBefore OrderStatus = "CheckedBefore"
+	StatusFailure       OrderStatus = "Failure"
+	StatusExpired       OrderStatus = "Expired"
+	StatusCancelled     OrderStatus = "Cancelled"
+)
+
 type Consumer struct {
-	Name    string `xml:"consumerName"`
-	Account string `xml:"consumerAccount"`
-	City    string `xml:"consumerCity"`
+	Name    string `xml:"consumerName" json:"name"`
+	Account string `xml:"consumerAccount" json:"account"`
+	City    string `xml:"consumerCity" json:"city"`
+
+	present bool // the response contained a consumer element
+}
+
+func (consumer *Consumer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain Consumer
+	var v plain
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*consumer = Consumer(v)
+	consumer.present = true
+	return nil
+}
+
+/*
+AmountEuros returns the amount of the order in euros, or in the main unit of
+its currency.
+*/
+func (order *Order) AmountEuros() float64 {
+	return Amount(order.Amount).Euros()
+}
+
+/*
+FormattedAmount returns the amount of the order formatted using Dutch
+conventions, for example "€ 19,99". The currency of the order is used, with
+EUR when it is empty.
+*/
+func (order *Order) FormattedAmount() string {
+	return formatAmount(order.Amount, order.Currency)
+}
+
+/*
+HasConsumer returns true when Mollie returned the details of the consumer.
+Mollie leaves them out when the transaction isn't paid.
+*/
+func (order *Order) HasConsumer() bool {
+	return order.Consumer.present
+}
+
+/*
+String returns a summary of the order that is safe to log. The account of the
+consumer is masked.
+*/
+func (order Order) String() string {
+	return fmt.Sprintf("transaction %s: %d %s, status %s, payed %t, consumer %s",
+		order.TransactionId, order.Amount, order.Currency, order.Status, order.Payed, order.Consumer)
+}
+
+/*
+String returns a summary of the consumer that is safe to log. Only the last 4
+characters of the account are shown.
+*/
+func (consumer Consumer) String() string {
+	return fmt.Sprintf("%s (%s), %s", consumer.Name, maskAccount(consumer.Account), consumer.City)
+}
+
+/*
+maskAccount replaces all but the last 4 characters of account with '*'.
+*/
+func maskAccount(account string) string {
+	runes := []rune(account)
+	for i := 0; i < len(runes)-4; i++ {
+		runes[i] = '*'
+	}
+	return string(runes)
 }
 
 func (resp *MollieResponse) IsSuccess() bool {
-	return resp.Order.Status == "Success"
+	return resp.Order.Status == StatusSuccess
+}
+
+/*
+IsPaid returns true when the status of the transaction is Success and Mollie
+also reports it as payed. IsSuccess only looks at the status, which can be
+Success while the payment was partial or reversed. Use IsPaid to decide
+whether to deliver the order.
+*/
+func (resp *MollieResponse) IsPaid() bool {
+	return resp.Order.Status == StatusSuccess && resp.Order.Payed
+}
+
+/*
+VerifyAmount returns an error for which errors.Is(err, ErrAmountMismatch) is
+true when the amount of the transaction isn't expectedCents. Use it before
+marking an order as paid, with the amount that was sent to Fetch.
+*/
+func (resp *MollieResponse) VerifyAmount(expectedCents int) error {
+	if resp.Order.Amount != expectedCents {
+		return wrap(ErrAmountMismatch, fmt.Errorf("amount of transaction %s is %d cents, expected %d cents",
+			resp.Order.TransactionId, resp.Order.Amount, expectedCents))
+	}
+	return nil
+}
+
+/*
+IsSuccessOrChecked returns true when the status of the transaction is Success
+or CheckedBefore, so a transaction that was paid and checked before counts as
+paid too. IsSuccess is only true for Success.
+*/
+func (resp *MollieResponse) IsSuccessOrChecked() bool {
+	return resp.IsSuccess() || resp.IsCheckedBefore()
 }
 
 func (resp *MollieResponse) IsCheckedBefore() bool {
-	return resp.Order.Status == "CheckedBefore"
+	return resp.Order.Status == StatusCheckedBefore
 }
 
 func (resp *MollieResponse) IsFailure() bool {
-	return resp.Order.Status == "Failure"
+	return resp.Order.Status == StatusFailure
 }
 
 func (resp *MollieResponse) IsExpired() bool {
-	return resp.Order.Status == "Expired"
+	return resp.Order.Status == StatusExpired
 }
 
 func (resp *MollieResponse) IsCancelled() bool {
-	return resp.Order.Status == "Cancelled"
+	return resp.Order.Status == StatusCancelled
 }
 
+/*
+Message returns the message that Mollie sent with the transaction, for
+example to explain its status. It is empty when Mollie didn't send one.
+*/
+func (resp *MollieResponse) Message() string {
+	return resp.Order.Message
+}
+
+/*
+Status returns the status of the transaction.
+*/
+func (resp *MollieResponse) Status() OrderStatus {
+	return resp.Order.Status
+}
+
+/*
+IsTerminal returns true when the status of the transaction won't change
+anymore: Success, Failure, Expired or Cancelled. Use it to decide when to
+stop polling.
+*/
+func (resp *MollieResponse) IsTerminal() bool {
+	switch resp.Order.Status {
+	case StatusSuccess, StatusFailure, StatusExpired, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+/*
+RequestID returns the id that Mollie assigned to the request, or an empty
+string when Mollie didn't send one.
+*/
+func (resp *MollieResponse) RequestID() string {
+	return resp.Header.Get(RequestIDHeader)
+}
+
+/*
+RedirectURL returns the URL of the bank that the consumer should be redirected
+to after Fetch. It returns an error when the response doesn't contain a valid
+URL, which happens when Fetch failed.
+*/
+func (resp *MollieResponse) RedirectURL() (*url.URL, error) {
+	if resp.Order.URL == "" {
+		return nil, fmt.Errorf("response contains no redirect URL")
+	}
+	u, err := url.Parse(resp.Order.URL)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, fmt.Errorf("redirect URL %q is not an absolute URL", resp.Order.URL)
+	}
+	return u, nil
+}
+
+/*
+IsOpen returns true when the transaction was created, but is not yet paid,
+failed, expired or cancelled.
+*/
+func (resp *MollieResponse) IsOpen() bool {
+	return resp.Order.Status == StatusOpen
+}
+
+/*
+Version is the version of this package.
+*/
+const Version = "0.1.0"
+
+/*
+DefaultUserAgent is the User-Agent header that is sent with every request,
+unless it is changed with SetUserAgent.
+*/
+const DefaultUserAgent = "go-mollie/" + Version
+
+/*
+The values of the "a" parameter that select the action of a request. They are
+only variables so they can be changed in an emergency, when Mollie renames
+an action before this package is updated. This is for advanced use only:
+change them before any request is sent, and never while requests are running.
+*/
+var (
+	ActionBankList = "banklist"
+	ActionFetch    = "fetch"
+	ActionCheck    = "check"
+)
+
+/*
+DefaultBaseURL is the URL of the Mollie iDEAL API.
+*/
+const DefaultBaseURL = "https://secure.mollie.nl/xml/ideal"
+
 /*
 NewMollie creates the main Mollie struct.
 
@@ -102,52 +658,857 @@ partnerId is the partnerId that you got from Mollie. If testmode is true the
 requests will be sent in testmode.
 */
 func NewMollie(partnerId int, testmode bool) (*Mollie, error) {
-	baseurl, err := url.Parse("https://secure.mollie.nl/xml/ideal")
+	var opts []Option
+	if testmode {
+		opts = append(opts, WithTestmode())
+	}
+	return NewMollieWithOptions(partnerId, opts...)
+}
+
+/*
+SetBaseURL replaces the URL of the Mollie API, for example to use a mock
+server in tests. The query of rawurl is ignored, because every request builds
+its own query.
+*/
+func (mollie *Mollie) SetBaseURL(rawurl string) error {
+	baseurl, err := url.Parse(rawurl)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if testmode {
-		q := baseurl.Query()
-		q.Set("testmode", "true")
-		baseurl.RawQuery = q.Encode()
+	if !baseurl.IsAbs() || baseurl.Host == "" {
+		return fmt.Errorf("base URL %q is not an absolute URL", rawurl)
+	}
+	baseurl.RawQuery = ""
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.baseurl = baseurl
+	return nil
+}
+
+/*
+Clone returns a copy of the client with the same settings, that can be
+changed without changing the original. The copy shares the http.Client, the
+rate limit, the circuit breaker and the Metadata of transactions with the
+original, so a Check on the copy finds the Metadata of a Fetch on the
+original. It has its own bank list cache, which starts with a copy of the
+fallback bank list.
+*/
+func (mollie *Mollie) Clone() *Mollie {
+	mollie.mu.RLock()
+	clone := &Mollie{settings: mollie.settings}
+	mollie.mu.RUnlock()
+
+	baseurl := *clone.baseurl
+	clone.baseurl = &baseurl
+	clone.ownsClient = false
+
+	mollie.bankCache.mu.Lock()
+	clone.bankCache.ttl = mollie.bankCache.ttl
+	if fallback := mollie.bankCache.fallback; fallback != nil {
+		clone.bankCache.fallback = fallback.copy()
 	}
+	mollie.bankCache.mu.Unlock()
+	return clone
+}
+
+/*
+CloneWithProfileKey returns a copy of the client, like Clone, that uses key as
+the profile key.
+*/
+func (mollie *Mollie) CloneWithProfileKey(key string) *Mollie {
+	clone := mollie.Clone()
+	clone.SetProfileKey(key)
+	return clone
+}
 
-	return &Mollie{baseurl: baseurl, partnerId: partnerId, testmode: testmode}, nil
+/*
+SetTestmode enables or disables testmode. When testmode is enabled the
+requests will be sent in testmode.
+*/
+func (mollie *Mollie) SetTestmode(testmode bool) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.testmode = testmode
 }
 
 /*
 SetProfileKey allows you to set the optional profilekey.
 */
 func (mollie *Mollie) SetProfileKey(key string) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
 	mollie.profileKey = key
 }
 
 /*
-BankList returns the banks that can be used right now.
+SetTruncateDescription controls what Fetch does with descriptions that are
+longer than MaxDescriptionLength. When truncate is false, the default, Fetch
+returns ErrDescriptionTooLong. When it is true, the description is shortened
+with TruncateDescription.
 */
-func (mollie *Mollie) BankList() (*BankResponse, error) {
-	u := *mollie.baseurl
-	q := u.Query()
-	q.Set("a", "banklist")
+func (mollie *Mollie) SetTruncateDescription(truncate bool) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.truncateDescription = truncate
+}
+
+/*
+SetPostFetch controls how Fetch sends its parameters. By default they are sent
+in the query string of a GET request, which means that the description and
+URLs end up in the access logs of servers and proxies. When post is true,
+Fetch sends a POST request with the parameters in the body instead.
+*/
+func (mollie *Mollie) SetPostFetch(post bool) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.postFetch = post
+}
+
+/*
+SetWarnOnTransactionMismatch controls what Check does when the response is
+for another transaction than the one that was requested, for example because
+of a caching proxy. By default Check returns an error for which
+errors.Is(err, ErrTransactionMismatch) is true. When warn is true, Check
+logs a warning and returns the response.
+*/
+func (mollie *Mollie) SetWarnOnTransactionMismatch(warn bool) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.warnOnMismatch = warn
+}
+
+/*
+SetPostRequests controls how Fetch and Check send their parameters, like
+SetPostFetch. When post is true, both send a POST request with the
+parameters, including the partner id and the profile key, in the body, so
+they don't end up in access logs.
+
+Mollie only accepts the partner id and the profile key as parameters, in the
+query string or in the body of a POST request, and not in HTTP headers.
+*/
+func (mollie *Mollie) SetPostRequests(post bool) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.postFetch = post
+	mollie.postCheck = post
+}
+
+/*
+SetAllowHTTP controls whether Fetch accepts http report and return URLs. By
+default they must be https URLs, because Mollie needs to reach them over the
+internet. Allow http for local testing only.
+*/
+func (mollie *Mollie) SetAllowHTTP(allow bool) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.allowHTTP = allow
+}
+
+/*
+SetHTTPClient sets the http.Client that is used for all requests to Mollie,
+for example with a transport that is tuned differently than NewTransport.
+When client is nil, a new default client is used.
+*/
+func (mollie *Mollie) SetHTTPClient(client *http.Client) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	if client == nil {
+		mollie.httpClient = newDefaultClient()
+		mollie.ownsClient = true
+		return
+	}
+	mollie.httpClient = client
+	mollie.ownsClient = false
+}
+
+/*
+DefaultMaxIdleConnsPerHost is the number of idle connections to Mollie that
+the transport of NewTransport keeps open.
+*/
+const DefaultMaxIdleConnsPerHost = 16
+
+/*
+NewTransport returns the transport of the default http.Client. It is a clone
+of http.DefaultTransport, so it uses keep-alive and HTTP/2 when the server
+supports it, and keeps DefaultMaxIdleConnsPerHost idle connections open
+instead of 2, so connections are reused under load. Idle connections are
+closed after 90 seconds.
+*/
+func NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.ForceAttemptHTTP2 = true
+	return transport
+}
+
+func newDefaultClient() *http.Client {
+	return &http.Client{Transport: NewTransport()}
+}
+
+/*
+SetInsecureSkipVerify disables verification of the TLS certificate of the
+server when skip is true, for example for a staging environment with a
+self-signed certificate. Never use it in production. A warning is logged the
+first time it is enabled.
+
+The client gets a copy of the transport of the current http.Client, so a
+client set with SetHTTPClient isn't changed. It returns an error when that
+transport isn't an *http.Transport.
+*/
+func (mollie *Mollie) SetInsecureSkipVerify(skip bool) error {
+	mollie.mu.Lock()
+	base := mollie.httpClient
+	if base == nil {
+		base = http.DefaultClient
+	}
+	transport, ok := base.Transport.(*http.Transport)
+	if base.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		mollie.mu.Unlock()
+		return fmt.Errorf("transport of the http.Client is not an *http.Transport")
+	}
+	transport = transport.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = skip
+	client := *base
+	client.Transport = transport
+	mollie.httpClient = &client
+	mollie.ownsClient = true
+	warn := skip && !mollie.warnedTLS
+	if warn {
+		mollie.warnedTLS = true
+	}
+	mollie.mu.Unlock()
+
+	if warn {
+		mollie.debugf("WARNING: TLS certificate verification is disabled, don't use this in production")
+	}
+	return nil
+}
+
+/*
+Close closes the idle connections of the http.Client when it was created by
+this package. A client that was set with SetHTTPClient belongs to the caller
+and is left alone.
+*/
+func (mollie *Mollie) Close() error {
+	mollie.mu.RLock()
+	defer mollie.mu.RUnlock()
+	if mollie.ownsClient && mollie.httpClient != nil {
+		mollie.httpClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+/*
+SetUserAgent sets the User-Agent header that is sent with every request. When
+userAgent is empty, DefaultUserAgent is used.
+*/
+func (mollie *Mollie) SetUserAgent(userAgent string) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.userAgent = userAgent
+}
+
+/*
+SetRequestInterceptor sets a function that is called with every request
+before it is sent, including retries. It can change the headers of the
+request, for example to replace the default "Accept: application/xml".
+*/
+func (mollie *Mollie) SetRequestInterceptor(f func(*http.Request)) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.requestInterceptor = f
+}
+
+/*
+SetResponseInterceptor sets a function that is called with every response
+that is received, including responses that will be retried. It should only
+inspect the response and must not read the body.
+*/
+func (mollie *Mollie) SetResponseInterceptor(f func(*http.Response)) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.responseInterceptor = f
+}
+
+/*
+SetTimeout sets the maximum duration of a single attempt of a request to
+Mollie, including reading the response body. Every retry gets the full
+timeout again; use the context of the call to limit the total duration. A
+zero duration means no timeout.
+*/
+func (mollie *Mollie) SetTimeout(d time.Duration) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.timeout = d
+}
+
+/*
+SetOperationTimeout sets the timeout of the operation op, one of "banklist",
+"fetch" and "check", for example to give Fetch more time than BankList. It
+replaces the timeout of SetTimeout for that operation. A zero duration
+removes the timeout of the operation, so the timeout of SetTimeout is used.
+*/
+func (mollie *Mollie) SetOperationTimeout(op string, d time.Duration) error {
+	switch op {
+	case "banklist", "fetch", "check":
+	default:
+		return fmt.Errorf("unknown operation %q", op)
+	}
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	// The map is shared with clones, so it is replaced instead of changed.
+	timeouts := make(map[string]time.Duration, len(mollie.opTimeouts)+1)
+	for k, v := range mollie.opTimeouts {
+		timeouts[k] = v
+	}
+	if d == 0 {
+		delete(timeouts, op)
+	} else {
+		timeouts[op] = d
+	}
+	mollie.opTimeouts = timeouts
+	return nil
+}
+
+/*
+withTimeout derives a context from ctx for a single attempt of the operation
+op, that is bound by the configured timeout. The returned cancel function
+must always be called.
+*/
+func (mollie *Mollie) withTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	mollie.mu.RLock()
+	timeout := mollie.timeout
+	if d, ok := mollie.opTimeouts[op]; ok {
+		timeout = d
+	}
+	mollie.mu.RUnlock()
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+/*
+SetLogger sets the logger that receives debug output. When logger is nil,
+nothing is logged.
+*/
+func (mollie *Mollie) SetLogger(logger Logger) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.logger = logger
+}
+
+func (mollie *Mollie) debugf(format string, args ...interface{}) {
+	mollie.mu.RLock()
+	logger := mollie.logger
+	mollie.mu.RUnlock()
+	if logger != nil {
+		logger.Debugf(format, args...)
+	}
+}
+
+/*
+endpoint returns a copy of the base URL, a new query for action and the
+profile key. The query contains the action and, in testmode, the testmode
+parameter. Because url.Values.Encode sorts the parameters by key, the query
+string of every request is the same for the same parameters.
+*/
+func (mollie *Mollie) endpoint(action string) (url.URL, url.Values, string) {
+	mollie.mu.RLock()
+	defer mollie.mu.RUnlock()
+	q := url.Values{}
+	q.Set("a", action)
+	if mollie.testmode {
+		q.Set("testmode", "true")
+	}
+	return *mollie.baseurl, q, mollie.profileKey
+}
+
+/*
+redact returns a string representation of u with the partner id and profile
+key replaced, so it can safely be logged.
+*/
+func redact(u *url.URL) string {
+	r := *u
+	q := r.Query()
+	for _, key := range []string{"partnerid", "profile_key"} {
+		if q.Get(key) != "" {
+			q.Set(key, "REDACTED")
+		}
+	}
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+/*
+client returns the http.Client for a request. The redirect policy is applied
+to a copy of the client, so the client itself isn't changed.
+*/
+func (mollie *Mollie) client() *http.Client {
+	mollie.mu.RLock()
+	defer mollie.mu.RUnlock()
+	client := mollie.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	policy := mollie.redirectPolicy
+	if policy == nil {
+		if client.CheckRedirect != nil {
+			return client
+		}
+		policy = SameHostRedirectPolicy
+	}
+	c := *client
+	c.CheckRedirect = policy
+	return &c
+}
+
+/*
+SameHostRedirectPolicy is the default redirect policy. It only follows
+redirects to the host of the original request, and not from https to http, so
+the partner id in the URL is never sent to another host or in cleartext. It
+stops after 10 redirects.
+*/
+func SameHostRedirectPolicy(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+	if req.URL.Host != via[0].URL.Host {
+		return fmt.Errorf("refusing redirect from %s to another host %s", via[0].URL.Host, req.URL.Host)
+	}
+	if via[0].URL.Scheme == "https" && req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing redirect from https to %s", req.URL.Scheme)
+	}
+	return nil
+}
+
+/*
+SetRedirectPolicy sets the function that decides whether to follow a
+redirect, like http.Client.CheckRedirect. When policy is nil, the
+CheckRedirect of the http.Client is used, or SameHostRedirectPolicy when the
+client has none.
+*/
+func (mollie *Mollie) SetRedirectPolicy(policy func(req *http.Request, via []*http.Request) error) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.redirectPolicy = policy
+}
+
+/*
+get sends a GET request for u using ctx, for the operation op.
+*/
+func (mollie *Mollie) get(ctx context.Context, op string, u *url.URL) (*http.Response, error) {
+	return mollie.send(ctx, op, "GET", u, nil)
+}
+
+/*
+post sends a POST request to u with form as the body using ctx, for the
+operation op.
+*/
+func (mollie *Mollie) post(ctx context.Context, op string, u *url.URL, form url.Values) (*http.Response, error) {
+	return mollie.send(ctx, op, "POST", u, form)
+}
+
+/*
+send sends a request for u using ctx. When form is not nil, it is sent as the
+body of the request. Every attempt is bound by the timeout of op, and failed
+attempts are retried according to the retry policy. If ctx is done before or
+during the request, ctx.Err() is returned. When the circuit breaker is open,
+ErrCircuitOpen is returned.
+*/
+func (mollie *Mollie) send(ctx context.Context, op, method string, u *url.URL, form url.Values) (*http.Response, error) {
+	mollie.mu.RLock()
+	breaker := mollie.breaker
+	mollie.mu.RUnlock()
+	if breaker == nil {
+		return mollie.sendRetry(ctx, op, method, u, form)
+	}
+
+	if err := breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := mollie.sendRetry(ctx, op, method, u, form)
+	switch {
+	case err == nil:
+		breaker.record(resp.StatusCode >= 500)
+	case errors.Is(err, context.Canceled):
+		// The caller gave up, which says nothing about Mollie. A timeout
+		// does count as a failure.
+		breaker.release()
+	default:
+		breaker.record(true)
+	}
+	return resp, err
+}
+
+/*
+sendRetry sends the request of send, and retries it.
+*/
+func (mollie *Mollie) sendRetry(ctx context.Context, op, method string, u *url.URL, form url.Values) (*http.Response, error) {
+	mollie.mu.RLock()
+	userAgent := mollie.userAgent
+	maxRetries := mollie.maxRetries
+	retryDelay := mollie.retryDelay
+	retryIf := mollie.retryIf
+	onRequest := mollie.requestInterceptor
+	onResponse := mollie.responseInterceptor
+	limiter := mollie.limiter
+	mollie.mu.RUnlock()
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	requestID := requestIDFrom(ctx)
+	if retryIf == nil {
+		retryIf = shouldRetry
+	}
+
+	for n := 0; ; n++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if limiter != nil {
+			if err := limiter.wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		var body io.Reader
+		if form != nil {
+			body = strings.NewReader(form.Encode())
+		}
+		attemptCtx, cancel := mollie.withTimeout(ctx, op)
+		req, err := http.NewRequestWithContext(attemptCtx, method, u.String(), body)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Accept", "application/xml")
+		if requestID != "" {
+			req.Header.Set(ClientRequestIDHeader, requestID)
+		}
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if onRequest != nil {
+			onRequest(req)
+		}
+		logurl := redact(u)
+		mollie.debugf("[%s] %s %s", requestID, method, logurl)
+		resp, err := mollie.client().Do(req)
+		if ue, ok := err.(*url.Error); ok {
+			// url.Error contains the full URL, including the partner id
+			mollie.debugf("[%s] %s %s failed: %v", requestID, method, logurl, ue.Err)
+		} else if err != nil {
+			mollie.debugf("[%s] %s %s failed: %v", requestID, method, logurl, err)
+		} else {
+			mollie.debugf("[%s] %s %s: %s", requestID, method, logurl, resp.Status)
+			if onResponse != nil {
+				onResponse(resp)
+			}
+		}
+		if err != nil && ctx.Err() != nil {
+			cancel()
+			return nil, ctx.Err()
+		}
+		if n >= maxRetries || !retryIf(resp, err) {
+			if err != nil {
+				cancel()
+				return nil, wrap(ErrTransport, err)
+			}
+			// The timeout also covers reading the body.
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel()
+		if err := sleep(ctx, backoff(retryDelay, n)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+cancelBody cancels the context of an attempt when the body is closed.
+*/
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+/*
+SetStrictDecoding enables strict decoding of responses. In strict mode a
+response that contains elements that this package doesn't know about is an
+error, so changes to the API are noticed early, for example in integration
+tests. By default unknown elements are ignored.
+*/
+func (mollie *Mollie) SetStrictDecoding(strict bool) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.strictDecoding = strict
+}
+
+/*
+DefaultMaxXMLDepth is the maximum nesting depth of the elements of a response,
+unless it is changed with SetMaxXMLDepth.
+*/
+const DefaultMaxXMLDepth = 32
+
+/*
+SetMaxXMLDepth sets the maximum nesting depth of the elements of a response.
+A response with deeper nested elements is an error, so a broken or malicious
+response can't make the decoder do excessive work. When n is zero or less,
+DefaultMaxXMLDepth is used.
+*/
+func (mollie *Mollie) SetMaxXMLDepth(n int) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.maxXMLDepth = n
+}
+
+/*
+decodeOptions returns the settings for decode.
+*/
+func (mollie *Mollie) decodeOptions() decodeOptions {
+	mollie.mu.RLock()
+	defer mollie.mu.RUnlock()
+	return decodeOptions{strict: mollie.strictDecoding, maxDepth: mollie.maxXMLDepth}
+}
+
+/*
+DefaultMaxBodySize is the maximum size of a response body, unless it is
+changed with SetMaxBodySize.
+*/
+const DefaultMaxBodySize = 1 << 20
+
+/*
+SetMaxBodySize sets the maximum size in bytes of a response body. Larger
+responses fail with ErrBodyTooLarge. When n is zero or less,
+DefaultMaxBodySize is used.
+*/
+func (mollie *Mollie) SetMaxBodySize(n int64) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.maxBody = n
+}
+
+/*
+readBody reads the body of resp, up to the maximum body size.
+*/
+func (mollie *Mollie) readBody(resp *http.Response) ([]byte, error) {
+	mollie.mu.RLock()
+	limit := mollie.maxBody
+	mollie.mu.RUnlock()
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		if resp.Request != nil && resp.Request.Context().Err() != nil {
+			return nil, resp.Request.Context().Err()
+		}
+		return nil, wrap(ErrTransport, err)
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("response is larger than %d bytes: %w", limit, ErrBodyTooLarge)
+	}
+	return body, nil
+}
+
+/*
+checkStatus returns an *HTTPStatusError when resp does not have status code
+200.
+*/
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == 200 {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &HTTPStatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       string(body),
+	}
+}
+
+/*
+BankListURL returns the URL that BankList requests. Its parameters are sorted
+like those of FetchURL.
+*/
+func (mollie *Mollie) BankListURL() *url.URL {
+	u, q, profileKey := mollie.endpoint(ActionBankList)
+	if len(profileKey) > 0 {
+		q.Set("profile_key", profileKey)
+	}
+	u.RawQuery = q.Encode()
+	return &u
+}
+
+/*
+FetchURL returns the URL that Fetch requests for request, without sending it.
+It returns an error when request is invalid. When Fetch sends a POST request,
+the query string of the URL is sent as the body instead.
+
+The parameters are always sorted by name, and the values of a parameter from
+Extra keep their order, so the same request always results in the same URL.
+Adding a parameter never changes the order of the others. For example, with
+testmode enabled:
+
+	https://secure.mollie.nl/xml/ideal?a=fetch&amount=1000&bank_id=31&currency=EUR&description=Order+1&partnerid=1234&reporturl=...&returnurl=...&testmode=true
+*/
+func (mollie *Mollie) FetchURL(request *FetchRequest) (*url.URL, error) {
+	mollie.mu.RLock()
+	truncate := mollie.truncateDescription
+	allowHTTP := mollie.allowHTTP
+	mollie.mu.RUnlock()
+	if request != nil && truncate {
+		r := *request
+		r.Description = TruncateDescription(r.Description)
+		request = &r
+	}
+	if err := request.validate(allowHTTP); err != nil {
+		return nil, err
+	}
+
+	u, q, profileKey := mollie.endpoint(ActionFetch)
+	if len(request.ProfileKey) > 0 {
+		profileKey = request.ProfileKey
+	}
+	q.Set("partnerid", strconv.FormatInt(int64(mollie.partnerId), 10))
+	if len(profileKey) > 0 {
+		q.Set("profile_key", profileKey)
+	}
+	q.Set("amount", request.Amount.String())
+	q.Set("currency", string(request.currency()))
+	q.Set("bank_id", strconv.FormatInt(int64(request.BankId), 10))
+	q.Set("description", request.Description)
+	q.Set("reporturl", request.Reporturl.String())
+	q.Set("returnurl", request.Returnurl.String())
+	if len(request.IdempotencyKey) > 0 {
+		q.Set("idempotency_key", request.IdempotencyKey)
+	}
+	if len(request.Locale) > 0 {
+		q.Set("locale", request.Locale)
+	}
+	for key, values := range request.Extra {
+		if _, ok := q[key]; !ok {
+			q[key] = append([]string(nil), values...)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return &u, nil
+}
+
+/*
+CheckURL returns the URL that Check requests for transactionId. Its
+parameters are sorted like those of FetchURL.
+*/
+func (mollie *Mollie) CheckURL(transactionId string) *url.URL {
+	u, q, profileKey := mollie.endpoint(ActionCheck)
+	q.Set("partnerid", strconv.FormatInt(int64(mollie.partnerId), 10))
+	if len(profileKey) > 0 {
+		q.Set("profile_key", profileKey)
+	}
+	q.Set("transaction_id", transactionId)
 	u.RawQuery = q.Encode()
+	return &u
+}
+
+/*
+BankList returns the banks that can be used right now. When Mollie returns no
+banks, BankList returns the empty response and ErrNoBanksAvailable. When a
+fallback is set with SetBankListFallback, BankList returns the fallback
+instead of an error when Mollie can't be reached or has no banks. A
+*MollieError is always returned.
+*/
+func (mollie *Mollie) BankList() (*BankResponse, error) {
+	return mollie.BankListContext(context.Background())
+}
 
-	resp, err := http.Get(u.String())
+/*
+BankListContext is like BankList, but the request is bound to ctx.
+*/
+func (mollie *Mollie) BankListContext(ctx context.Context) (*BankResponse, error) {
+	resp, err := mollie.liveBankList(ctx)
+	if err == nil || ctx.Err() != nil || !usesFallback(err) {
+		return resp, err
+	}
+	if fallback := mollie.bankListFallback(); fallback != nil {
+		mollie.debugf("using the fallback bank list: %v", err)
+		return fallback, nil
+	}
+	return resp, err
+}
+
+/*
+usesFallback returns true when BankList returns the fallback list for err:
+when Mollie can't be reached, fails with a 5xx status or has no banks. An
+error that Mollie returned, like an invalid profile key, usually means that
+the configuration is wrong, so it is returned as it is.
+*/
+func usesFallback(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+	return errors.Is(err, ErrTransport) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrNoBanksAvailable)
+}
+
+/*
+liveBankList is like BankListContext, but never returns the fallback.
+*/
+func (mollie *Mollie) liveBankList(ctx context.Context) (*BankResponse, error) {
+	ctx, id := mollie.withRequestID(ctx)
+	ctx, finish := mollie.trace(ctx, "banklist")
+	resp, err := mollie.cachedBankList(ctx)
+	err = wrapRequestID(id, err)
+	finish(err)
+	return resp, err
+}
+
+/*
+bankList requests the bank list from Mollie.
+*/
+func (mollie *Mollie) bankList(ctx context.Context) (*BankResponse, error) {
+	resp, err := mollie.get(ctx, "banklist", mollie.BankListURL())
 	if err != nil {
 		return nil, err
 	}
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("StatusCode not 200, but %d", resp.StatusCode)
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	body, err := mollie.readBody(resp)
+	if err != nil {
+		return nil, err
 	}
-	decoder := xml.NewDecoder(resp.Body)
-	res := BankResponse{}
-	err = decoder.Decode(&res)
+	res, err := decodeBankResponse(body, mollie.decodeOptions())
 	if err != nil {
 		return nil, err
 	}
-	return &res, nil
+	res.Header = resp.Header
+	res.ClientRequestID = requestIDFrom(ctx)
+	if len(res.Banks) == 0 {
+		return res, ErrNoBanksAvailable
+	}
+	return res, nil
 }
 
 /*
@@ -158,60 +1519,143 @@ that can be set in the request parameter.
 After Fetch you should redirect the client to URL in MollieResponse.
 */
 func (mollie *Mollie) Fetch(request *FetchRequest) (*MollieResponse, error) {
-	u := *mollie.baseurl
-	q := u.Query()
-	q.Set("a", "fetch")
-	q.Set("partnerid", strconv.FormatInt(int64(mollie.partnerId), 10))
-	if len(mollie.profileKey) > 0 {
-		q.Set("profile_key", mollie.profileKey)
+	return mollie.FetchContext(context.Background(), request)
+}
+
+/*
+FetchContext is like Fetch, but the request is bound to ctx.
+*/
+func (mollie *Mollie) FetchContext(ctx context.Context, request *FetchRequest) (*MollieResponse, error) {
+	ctx, id := mollie.withRequestID(ctx)
+	ctx, finish := mollie.trace(ctx, "fetch")
+	resp, err := mollie.fetch(ctx, request)
+	err = wrapRequestID(id, err)
+	finish(err)
+	return resp, err
+}
+
+func (mollie *Mollie) fetch(ctx context.Context, request *FetchRequest) (*MollieResponse, error) {
+	u, err := mollie.FetchURL(request)
+	if err != nil {
+		return nil, err
 	}
-	q.Set("amount", strconv.FormatInt(int64(request.Amount), 10))
-	q.Set("bank_id", strconv.FormatInt(int64(request.BankId), 10))
-	q.Set("description", request.Description)
-	q.Set("reporturl", request.Reporturl.String())
-	q.Set("returnurl", request.Returnurl.String())
-	u.RawQuery = q.Encode()
 
-	resp, err := http.Get(u.String())
+	mollie.mu.RLock()
+	postFetch := mollie.postFetch
+	mollie.mu.RUnlock()
+
+	var resp *http.Response
+	if postFetch {
+		form := u.Query()
+		u.RawQuery = ""
+		resp, err = mollie.post(ctx, "fetch", u, form)
+	} else {
+		resp, err = mollie.get(ctx, "fetch", u)
+	}
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	decoder := xml.NewDecoder(resp.Body)
-	res := MollieResponse{}
-	err = decoder.Decode(&res)
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	body, err := mollie.readBody(resp)
 	if err != nil {
 		return nil, err
 	}
+	res, err := decodeMollieResponse(body, mollie.decodeOptions())
+	if err != nil {
+		return nil, err
+	}
+	res.Header = resp.Header
+	res.ClientRequestID = requestIDFrom(ctx)
+	if res.Message() != "" {
+		mollie.debugf("[%s] fetch message: %s", res.ClientRequestID, res.Message())
+	}
+	if len(request.Metadata) > 0 && res.Order.TransactionId != "" {
+		mollie.metadata.put(res.Order.TransactionId, request.Metadata)
+		res.Metadata = request.Metadata
+	}
 
-	return &res, nil
+	return res, nil
 }
 
 /*
 Check checks if the transaction is completed. It should be called when Mollie
 calls you report url. Pass the transactionId of the transaction that you want
-to check.
+to check. When Mollie doesn't know the transaction, Check returns an error for
+which errors.Is(err, ErrTransactionNotFound) is true. When Mollie returns
+another transaction, Check returns ErrTransactionMismatch, unless
+SetWarnOnTransactionMismatch is enabled.
 */
 func (mollie *Mollie) Check(transactionId string) (*MollieResponse, error) {
-	u := *mollie.baseurl
-	q := u.Query()
-	q.Set("a", "check")
-	q.Set("partnerid", strconv.FormatInt(int64(mollie.partnerId), 10))
-	q.Set("transaction_id", transactionId)
-	u.RawQuery = q.Encode()
+	return mollie.CheckContext(context.Background(), transactionId)
+}
+
+/*
+CheckContext is like Check, but the request is bound to ctx.
+*/
+func (mollie *Mollie) CheckContext(ctx context.Context, transactionId string) (*MollieResponse, error) {
+	ctx, id := mollie.withRequestID(ctx)
+	ctx, finish := mollie.trace(ctx, "check")
+	resp, err := mollie.check(ctx, transactionId)
+	err = wrapRequestID(id, err)
+	finish(err)
+	return resp, err
+}
+
+func (mollie *Mollie) check(ctx context.Context, transactionId string) (*MollieResponse, error) {
+	mollie.mu.RLock()
+	postCheck := mollie.postCheck
+	mollie.mu.RUnlock()
 
-	resp, err := http.Get(u.String())
+	u := mollie.CheckURL(transactionId)
+	var resp *http.Response
+	var err error
+	if postCheck {
+		form := u.Query()
+		u.RawQuery = ""
+		resp, err = mollie.post(ctx, "check", u, form)
+	} else {
+		resp, err = mollie.get(ctx, "check", u)
+	}
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	body, _ := ioutil.ReadAll(resp.Body)
-	fmt.Printf("\n%s\n", string(body))
-	res := MollieResponse{}
-	err = xml.Unmarshal(body, &res)
+
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+	body, err := mollie.readBody(resp)
 	if err != nil {
 		return nil, err
 	}
+	res, err := decodeMollieResponse(body, mollie.decodeOptions())
+	if err != nil {
+		return nil, err
+	}
+	res.Header = resp.Header
+	res.ClientRequestID = requestIDFrom(ctx)
+	// The body contains the name and account of the consumer, so it
+	// isn't logged.
+	mollie.debugf("[%s] check response: transaction %s, status %s",
+		res.ClientRequestID, res.Order.TransactionId, res.Status())
+	if res.Order.TransactionId != transactionId {
+		err := wrap(ErrTransactionMismatch, fmt.Errorf("Check returned transaction %q, but expected %q", res.Order.TransactionId, transactionId))
+		mollie.mu.RLock()
+		warnOnly := mollie.warnOnMismatch
+		mollie.mu.RUnlock()
+		if !warnOnly {
+			return nil, err
+		}
+		mollie.debugf("[%s] WARNING: %v", res.ClientRequestID, err)
+	}
+	if res.Message() != "" {
+		mollie.debugf("[%s] check message: %s", res.ClientRequestID, res.Message())
+	}
+	res.Metadata = mollie.metadata.get(transactionId, res.IsTerminal())
 
-	return &res, nil
+	return res, nil
 }
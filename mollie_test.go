@@ -0,0 +1,367 @@
+package mollie_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+/*
+testLogger collects the debug output of a client.
+*/
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCheckTransactionMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		warn    bool
+		wantErr bool
+	}{
+		{"strict", false, true},
+		{"warn", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mollietest.NewServer()
+			defer s.Close()
+			logger := &testLogger{}
+			s.Mollie.SetLogger(logger)
+			s.Mollie.SetWarnOnTransactionMismatch(tt.warn)
+			// The fixture is for mollietest.TransactionId.
+			s.SetResponse("check", http.StatusOK, mollietest.CheckXML)
+
+			resp, err := s.Mollie.Check("other")
+			if tt.wantErr {
+				if !errors.Is(err, mollie.ErrTransactionMismatch) {
+					t.Fatalf("err = %v, want ErrTransactionMismatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if resp.Order.TransactionId != mollietest.TransactionId {
+				t.Errorf("transaction = %q, want %q", resp.Order.TransactionId, mollietest.TransactionId)
+			}
+			if !logger.contains("WARNING") {
+				t.Errorf("no warning logged, got %q", logger.lines)
+			}
+		})
+	}
+}
+
+func TestCloneSharesMetadata(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	clone := s.Mollie.CloneWithProfileKey("profile")
+
+	reporturl, _ := url.Parse("https://example.com/report")
+	returnurl, _ := url.Parse("https://example.com/return")
+	_, err := clone.Fetch(&mollie.FetchRequest{
+		Amount:      1999,
+		BankId:      31,
+		Description: "Order 1",
+		Reporturl:   reporturl,
+		Returnurl:   returnurl,
+		Metadata:    "order-1",
+	})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	resp, err := s.Mollie.Check(mollietest.TransactionId)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if resp.Metadata != "order-1" {
+		t.Errorf("Metadata = %q, want order-1", resp.Metadata)
+	}
+}
+
+func TestCheckDoesNotLogConsumer(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	logger := &testLogger{}
+	s.Mollie.SetLogger(logger)
+
+	if _, err := s.Mollie.Check(mollietest.TransactionId); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	for _, secret := range []string{"Janssen", "NL91ABNA0417164300", "Amsterdam"} {
+		if logger.contains(secret) {
+			t.Errorf("log contains %q: %q", secret, logger.lines)
+		}
+	}
+	if !logger.contains("status Success") {
+		t.Errorf("log doesn't contain the status: %q", logger.lines)
+	}
+}
+
+/*
+newFetchRequest returns a valid request for the fake server.
+*/
+func newFetchRequest() *mollie.FetchRequest {
+	reporturl, _ := url.Parse("https://example.com/report")
+	returnurl, _ := url.Parse("https://example.com/return")
+	return &mollie.FetchRequest{
+		Amount:      1999,
+		BankId:      31,
+		Description: "Order 1",
+		Reporturl:   reporturl,
+		Returnurl:   returnurl,
+	}
+}
+
+func TestConcurrentSetProfileKeyAndFetch(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			s.Mollie.SetProfileKey(fmt.Sprintf("profile-%d", i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Mollie.Fetch(newFetchRequest()); err != nil {
+				t.Errorf("Fetch: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, q := range s.Requests() {
+		if key := q.Get("profile_key"); key != "" && !strings.HasPrefix(key, "profile-") {
+			t.Errorf("profile_key = %q", key)
+		}
+	}
+}
+
+func TestCheckLatin1(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	body := strings.Replace(mollietest.CheckXML, `<?xml version="1.0"?>`, `<?xml version="1.0" encoding="ISO-8859-1"?>`, 1)
+	body = strings.Replace(body, "Hr J Janssen", "Hr J M\xfcller-Cr\xe9mieux", 1)
+	s.SetResponse("check", http.StatusOK, body)
+
+	resp, err := s.Mollie.Check(mollietest.TransactionId)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if got, want := resp.Order.Consumer.Name, "Hr J Müller-Crémieux"; got != want {
+		t.Errorf("Consumer.Name = %q, want %q", got, want)
+	}
+}
+
+func TestCheckTransactionNotFound(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	s.SetResponse("check", http.StatusOK, `<?xml version="1.0"?>
+<response>
+	<item type="error">
+		<errorcode>-10</errorcode>
+		<message>This is an unknown order.</message>
+	</item>
+</response>`)
+
+	_, err := s.Mollie.Check("unknown")
+	if !errors.Is(err, mollie.ErrTransactionNotFound) {
+		t.Fatalf("err = %v, want ErrTransactionNotFound", err)
+	}
+	var mollieErr *mollie.MollieError
+	if !errors.As(err, &mollieErr) {
+		t.Fatalf("err = %v, want a *MollieError", err)
+	}
+	if mollieErr.Code != mollie.CodeUnknownOrder {
+		t.Errorf("Code = %d, want %d", mollieErr.Code, mollie.CodeUnknownOrder)
+	}
+	if mollieErr.Message != "This is an unknown order." {
+		t.Errorf("Message = %q", mollieErr.Message)
+	}
+}
+
+func TestQueryTestmode(t *testing.T) {
+	tests := []struct {
+		testmode bool
+		banklist string
+		check    string
+	}{
+		{false, "a=banklist", "a=check&partnerid=1234&transaction_id=abc"},
+		{true, "a=banklist&testmode=true", "a=check&partnerid=1234&testmode=true&transaction_id=abc"},
+	}
+	for _, tt := range tests {
+		m, err := mollie.NewMollie(1234, tt.testmode)
+		if err != nil {
+			t.Fatalf("NewMollie: %v", err)
+		}
+		// Twice, to make sure that the query doesn't change between calls.
+		for i := 0; i < 2; i++ {
+			if got := m.BankListURL().RawQuery; got != tt.banklist {
+				t.Errorf("testmode %v: BankListURL query = %q, want %q", tt.testmode, got, tt.banklist)
+			}
+			if got := m.CheckURL("abc").RawQuery; got != tt.check {
+				t.Errorf("testmode %v: CheckURL query = %q, want %q", tt.testmode, got, tt.check)
+			}
+		}
+	}
+}
+
+func TestCancelMidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(m *mollie.Mollie, ctx context.Context) error
+	}{
+		{"BankList", func(m *mollie.Mollie, ctx context.Context) error {
+			_, err := m.BankListContext(ctx)
+			return err
+		}},
+		{"Fetch", func(m *mollie.Mollie, ctx context.Context) error {
+			_, err := m.FetchContext(ctx, newFetchRequest())
+			return err
+		}},
+		{"Check", func(m *mollie.Mollie, ctx context.Context) error {
+			_, err := m.CheckContext(ctx, mollietest.TransactionId)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				slowHandler(w, r)
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-started
+				cancel()
+			}()
+			if err := tt.call(m, ctx); err != context.Canceled {
+				t.Errorf("err = %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+func TestAcceptHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		interceptor func(*http.Request)
+		want        string
+	}{
+		{"default", nil, "application/xml"},
+		{"interceptor", func(r *http.Request) { r.Header.Set("Accept", "text/xml") }, "text/xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var accept []string
+			m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				accept = append(accept, r.Header.Get("Accept"))
+				mu.Unlock()
+				switch r.FormValue("a") {
+				case "banklist":
+					fmt.Fprint(w, mollietest.BankListXML)
+				case "fetch":
+					fmt.Fprint(w, mollietest.FetchXML)
+				case "check":
+					fmt.Fprint(w, mollietest.CheckXML)
+				}
+			})
+			if tt.interceptor != nil {
+				m.SetRequestInterceptor(tt.interceptor)
+			}
+			if _, err := m.BankList(); err != nil {
+				t.Fatalf("BankList: %v", err)
+			}
+			if _, err := m.Fetch(newFetchRequest()); err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if _, err := m.Check(mollietest.TransactionId); err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(accept) != 3 {
+				t.Fatalf("requests = %d, want 3", len(accept))
+			}
+			for _, got := range accept {
+				if got != tt.want {
+					t.Errorf("Accept = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchURL(t *testing.T) {
+	m, err := mollie.NewMollie(1234, true)
+	if err != nil {
+		t.Fatalf("NewMollie: %v", err)
+	}
+	request := newFetchRequest()
+	request.Description = "Order 1"
+	request.Extra = url.Values{"z": {"2", "1"}, "b": {"x"}}
+
+	u, err := m.FetchURL(request)
+	if err != nil {
+		t.Fatalf("FetchURL: %v", err)
+	}
+	want := "https://secure.mollie.nl/xml/ideal?a=fetch&amount=1999&b=x&bank_id=31&currency=EUR" +
+		"&description=Order+1&partnerid=1234&reporturl=https%3A%2F%2Fexample.com%2Freport" +
+		"&returnurl=https%3A%2F%2Fexample.com%2Freturn&testmode=true&z=2&z=1"
+	if got := u.String(); got != want {
+		t.Errorf("FetchURL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSameHostRedirectPolicy(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  bool
+	}{
+		{"https://secure.mollie.nl/xml/ideal", "https://secure.mollie.nl/xml/ideal2", false},
+		{"http://localhost:8080/xml/ideal", "http://localhost:8080/xml/ideal2", false},
+		{"http://secure.mollie.nl/xml/ideal", "https://secure.mollie.nl/xml/ideal", false},
+		{"https://secure.mollie.nl/xml/ideal", "https://example.com/xml/ideal", true},
+		{"https://secure.mollie.nl/xml/ideal", "http://secure.mollie.nl/xml/ideal", true},
+	}
+	for _, tt := range tests {
+		from, _ := http.NewRequest("GET", tt.from, nil)
+		to, _ := http.NewRequest("GET", tt.to, nil)
+		err := mollie.SameHostRedirectPolicy(to, []*http.Request{from})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("redirect from %s to %s: err = %v, wantErr %v", tt.from, tt.to, err, tt.wantErr)
+		}
+	}
+}
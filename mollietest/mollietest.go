@@ -0,0 +1,201 @@
+/*
+mollietest.go - a fake Mollie iDEAL API server for tests
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+// Package mollietest provides a fake Mollie iDEAL API server for tests.
+package mollietest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pstuifzand/go-mollie"
+)
+
+/*
+PartnerId is the partner id of the Mollie client of a Server.
+*/
+const PartnerId = 123456
+
+/*
+TransactionId is the transaction id in the default fetch response. The
+default check response contains the transaction id of the request instead.
+*/
+const TransactionId = "482d599bbcc7795727650330ad65fe9b"
+
+/*
+Default responses of the server, by action.
+*/
+const (
+	BankListXML = `<?xml version="1.0"?>
+<response>
+	<bank>
+		<bank_id>0031</bank_id>
+		<bank_name>ABN AMRO</bank_name>
+	</bank>
+	<bank>
+		<bank_id>0721</bank_id>
+		<bank_name>ING</bank_name>
+	</bank>
+	<bank>
+		<bank_id>0021</bank_id>
+		<bank_name>Rabobank</bank_name>
+	</bank>
+</response>`
+
+	FetchXML = `<?xml version="1.0"?>
+<response>
+	<order>
+		<transaction_id>` + TransactionId + `</transaction_id>
+		<amount>1999</amount>
+		<currency>EUR</currency>
+		<URL>https://mijn.postbank.nl/internetbankieren/SesamLoginServlet?sessie=ideal&amp;trxid=003123456789123&amp;random=123456789abcdefgh</URL>
+		<message>Your iDEAL-payment has successfully been setup. Your customer should visit the given URL to make the payment</message>
+	</order>
+</response>`
+
+	CheckXML = `<?xml version="1.0"?>
+<response>
+	<order>
+		<transaction_id>` + TransactionId + `</transaction_id>
+		<amount>1999</amount>
+		<currency>EUR</currency>
+		<payed>true</payed>
+		<consumer>
+			<consumerName>Hr J Janssen</consumerName>
+			<consumerAccount>NL91ABNA0417164300</consumerAccount>
+			<consumerCity>Amsterdam</consumerCity>
+		</consumer>
+		<message>This iDEAL-order has successfuly been payed for, and this is the first time you check it.</message>
+		<status>Success</status>
+	</order>
+</response>`
+)
+
+type response struct {
+	status int
+	body   string
+	echo   bool // replace TransactionId with the requested transaction id
+}
+
+/*
+Server is a fake Mollie iDEAL API. It answers banklist, fetch and check
+requests with the responses that were set for the action, or with the default
+responses. Responses that were set are sent as they are.
+*/
+type Server struct {
+	*httptest.Server
+
+	// Mollie is a client that sends its requests to the server.
+	Mollie *mollie.Mollie
+
+	mu        sync.Mutex
+	responses map[string]response
+	next      map[string][]response
+	requests  []url.Values
+}
+
+/*
+NewServer starts a Server. Call Close when you are done with it.
+*/
+func NewServer() *Server {
+	s := &Server{
+		responses: map[string]response{
+			"banklist": {http.StatusOK, BankListXML, false},
+			"fetch":    {http.StatusOK, FetchXML, false},
+			"check":    {http.StatusOK, CheckXML, true},
+		},
+		next: make(map[string][]response),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	m, err := mollie.NewMollieWithOptions(PartnerId,
+		mollie.WithBaseURL(s.URL),
+		mollie.WithHTTPClient(s.Client()),
+	)
+	if err != nil {
+		s.Close()
+		panic(fmt.Sprintf("mollietest: %v", err))
+	}
+	s.Mollie = m
+	return s
+}
+
+/*
+SetResponse sets the response to every request for action, which is one of
+"banklist", "fetch" or "check".
+*/
+func (s *Server) SetResponse(action string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[action] = response{status, body, false}
+}
+
+/*
+SetNextResponse sets the response to the next request for action. It can be
+called multiple times to queue responses. After the queue is empty, the
+response set with SetResponse is used again.
+*/
+func (s *Server) SetNextResponse(action string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next[action] = append(s.next[action], response{status, body, false})
+}
+
+/*
+Requests returns the parameters of the requests that the server received.
+*/
+func (s *Server) Requests() []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]url.Values(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := r.Form.Get("a")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Form)
+	resp, ok := s.responses[action]
+	if queue := s.next[action]; len(queue) > 0 {
+		resp, ok = queue[0], true
+		s.next[action] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	body := resp.body
+	if id := r.Form.Get("transaction_id"); resp.echo && id != "" {
+		var escaped strings.Builder
+		xml.EscapeText(&escaped, []byte(id))
+		body = strings.Replace(body, TransactionId, escaped.String(), -1)
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(resp.status)
+	fmt.Fprint(w, body)
+}
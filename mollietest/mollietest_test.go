@@ -0,0 +1,22 @@
+package mollietest_test
+
+import (
+	"testing"
+
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestCheckEchoesTransactionId(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+
+	for _, id := range []string{mollietest.TransactionId, "abc123", "def456"} {
+		resp, err := s.Mollie.Check(id)
+		if err != nil {
+			t.Fatalf("Check(%q): %v", id, err)
+		}
+		if resp.Order.TransactionId != id {
+			t.Errorf("Check(%q) returned transaction %q", id, resp.Order.TransactionId)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+/*
+options.go - options for creating a Mollie client
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+Option configures a Mollie client created with NewMollieWithOptions.
+*/
+type Option func(mollie *Mollie) error
+
+/*
+NewMollieWithOptions creates the main Mollie struct, configured with opts.
+
+partnerId is the partnerId that you got from Mollie.
+*/
+func NewMollieWithOptions(partnerId int, opts ...Option) (*Mollie, error) {
+	if partnerId <= 0 {
+		return nil, fmt.Errorf("partnerId must be positive, but is %d", partnerId)
+	}
+	if int64(partnerId) > math.MaxInt32 {
+		return nil, fmt.Errorf("partnerId %d is too large", partnerId)
+	}
+	mollie := &Mollie{settings: settings{
+		partnerId:  partnerId,
+		httpClient: newDefaultClient(),
+		ownsClient: true,
+		metadata:   &metadataStore{},
+	}}
+	if err := mollie.SetBaseURL(DefaultBaseURL); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt(mollie); err != nil {
+			return nil, err
+		}
+	}
+	return mollie, nil
+}
+
+/*
+NewMollieFromEnv creates the main Mollie struct from the environment.
+MOLLIE_PARTNER_ID is required. MOLLIE_PROFILE_KEY is optional. Testmode is
+enabled when MOLLIE_TESTMODE is true, for example "true" or "1". An error
+starts with the name of the variable that is wrong.
+*/
+func NewMollieFromEnv() (*Mollie, error) {
+	value := os.Getenv("MOLLIE_PARTNER_ID")
+	if value == "" {
+		return nil, fmt.Errorf("MOLLIE_PARTNER_ID is not set")
+	}
+	partnerId, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("MOLLIE_PARTNER_ID: %w", err)
+	}
+
+	var opts []Option
+	if key := os.Getenv("MOLLIE_PROFILE_KEY"); key != "" {
+		opts = append(opts, WithProfileKey(key))
+	}
+	if value := os.Getenv("MOLLIE_TESTMODE"); value != "" {
+		testmode, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("MOLLIE_TESTMODE: %w", err)
+		}
+		if testmode {
+			opts = append(opts, WithTestmode())
+		}
+	}
+	mollie, err := NewMollieWithOptions(partnerId, opts...)
+	if err != nil {
+		// The options from the environment can't fail, so the partner id is
+		// invalid.
+		return nil, fmt.Errorf("MOLLIE_PARTNER_ID: %w", err)
+	}
+	return mollie, nil
+}
+
+/*
+WithTestmode sends all requests in testmode.
+*/
+func WithTestmode() Option {
+	return func(mollie *Mollie) error {
+		mollie.testmode = true
+		return nil
+	}
+}
+
+/*
+WithProfileKey sets the optional profile key.
+*/
+func WithProfileKey(key string) Option {
+	return func(mollie *Mollie) error {
+		mollie.SetProfileKey(key)
+		return nil
+	}
+}
+
+/*
+WithHTTPClient sets the http.Client that is used for all requests.
+*/
+func WithHTTPClient(client *http.Client) Option {
+	return func(mollie *Mollie) error {
+		mollie.SetHTTPClient(client)
+		return nil
+	}
+}
+
+/*
+WithTimeout sets the maximum duration of a single request.
+*/
+func WithTimeout(d time.Duration) Option {
+	return func(mollie *Mollie) error {
+		mollie.SetTimeout(d)
+		return nil
+	}
+}
+
+/*
+WithBaseURL replaces the URL of the Mollie API.
+*/
+func WithBaseURL(rawurl string) Option {
+	return func(mollie *Mollie) error {
+		return mollie.SetBaseURL(rawurl)
+	}
+}
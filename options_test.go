@@ -0,0 +1,52 @@
+package mollie_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+)
+
+func TestNewMollieFromEnv(t *testing.T) {
+	tests := []struct {
+		partnerId, profileKey, testmode string
+		wantErr                         string
+		wantTestmode                    bool
+	}{
+		{"1234", "", "", "", false},
+		{"1234", "profile", "true", "", true},
+		{"1234", "", "0", "", false},
+		{"", "", "", "MOLLIE_PARTNER_ID is not set", false},
+		{"abc", "", "", "MOLLIE_PARTNER_ID: ", false},
+		{"0", "", "", "MOLLIE_PARTNER_ID: partnerId must be positive, but is 0", false},
+		{"-5", "", "", "MOLLIE_PARTNER_ID: ", false},
+		{"99999999999", "", "", "MOLLIE_PARTNER_ID: ", false},
+		{"1234", "", "yes", "MOLLIE_TESTMODE: ", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("MOLLIE_PARTNER_ID", tt.partnerId)
+		t.Setenv("MOLLIE_PROFILE_KEY", tt.profileKey)
+		t.Setenv("MOLLIE_TESTMODE", tt.testmode)
+		m, err := mollie.NewMollieFromEnv()
+		if tt.wantErr != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("partner id %q, testmode %q: err = %v, want %q", tt.partnerId, tt.testmode, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("partner id %q, testmode %q: %v", tt.partnerId, tt.testmode, err)
+			continue
+		}
+		q := m.CheckURL("id").Query()
+		if q.Get("partnerid") != tt.partnerId {
+			t.Errorf("partnerid = %q, want %q", q.Get("partnerid"), tt.partnerId)
+		}
+		if q.Get("profile_key") != tt.profileKey {
+			t.Errorf("profile_key = %q, want %q", q.Get("profile_key"), tt.profileKey)
+		}
+		if got := q.Get("testmode") == "true"; got != tt.wantTestmode {
+			t.Errorf("testmode = %v, want %v", got, tt.wantTestmode)
+		}
+	}
+}
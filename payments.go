@@ -0,0 +1,280 @@
+/*
+payments.go - connect to the Mollie Payments API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+/*
+DefaultPaymentsURL is the URL of the Mollie Payments API.
+*/
+const DefaultPaymentsURL = "https://api.mollie.com/v2/payments"
+
+/*
+PaymentsClient is a client for the JSON based Mollie Payments API, which
+replaces the iDEAL API. It can be used next to Mollie while migrating.
+*/
+type PaymentsClient struct {
+	apiKey     string
+	baseurl    *url.URL
+	httpClient *http.Client
+}
+
+/*
+PaymentStatus is the status of a payment in the Payments API.
+*/
+type PaymentStatus string
+
+const (
+	PaymentOpen       PaymentStatus = "open"
+	PaymentCanceled   PaymentStatus = "canceled"
+	PaymentPending    PaymentStatus = "pending"
+	PaymentAuthorized PaymentStatus = "authorized"
+	PaymentExpired    PaymentStatus = "expired"
+	PaymentFailed     PaymentStatus = "failed"
+	PaymentPaid       PaymentStatus = "paid"
+)
+
+/*
+PaymentAmount is an amount of money as used by the Payments API, for example
+{"currency": "EUR", "value": "19.99"}.
+*/
+type PaymentAmount struct {
+	Currency string `json:"currency"`
+	Value    string `json:"value"`
+}
+
+/*
+NewPaymentAmount converts amount to a PaymentAmount in currency.
+*/
+func NewPaymentAmount(amount Amount, currency string) PaymentAmount {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return PaymentAmount{
+		Currency: currency,
+		Value:    fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100),
+	}
+}
+
+type paymentLink struct {
+	Href string `json:"href"`
+	Type string `json:"type"`
+}
+
+/*
+Payment is a payment in the Payments API.
+*/
+type Payment struct {
+	ID          string        `json:"id"`
+	Mode        string        `json:"mode"`
+	Status      PaymentStatus `json:"status"`
+	Amount      PaymentAmount `json:"amount"`
+	Description string        `json:"description"`
+	RedirectURL string        `json:"redirectUrl"`
+	WebhookURL  string        `json:"webhookUrl,omitempty"`
+	Links       struct {
+		Checkout *paymentLink `json:"checkout,omitempty"`
+	} `json:"_links"`
+}
+
+/*
+CheckoutURL returns the URL that the consumer should be redirected to, to pay
+for the payment. It is empty when the payment can't be paid anymore.
+*/
+func (payment *Payment) CheckoutURL() string {
+	if payment.Links.Checkout == nil {
+		return ""
+	}
+	return payment.Links.Checkout.Href
+}
+
+/*
+IsPaid returns true when the payment was paid.
+*/
+func (payment *Payment) IsPaid() bool {
+	return payment.Status == PaymentPaid
+}
+
+/*
+CreatePaymentRequest contains the parameters of a new payment.
+*/
+type CreatePaymentRequest struct {
+	Amount      PaymentAmount `json:"amount"`
+	Description string        `json:"description"`
+	RedirectURL string        `json:"redirectUrl"`
+	WebhookURL  string        `json:"webhookUrl,omitempty"`
+	Method      string        `json:"method,omitempty"`
+}
+
+/*
+PaymentsError is the error that the Payments API returns when it can't handle
+a request.
+*/
+type PaymentsError struct {
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+func (e *PaymentsError) Error() string {
+	return fmt.Sprintf("Mollie error %d %s: %s", e.Status, e.Title, e.Detail)
+}
+
+/*
+NewPaymentsClient creates a client for the Payments API. apiKey is the live or
+test API key that you got from Mollie.
+*/
+func NewPaymentsClient(apiKey string) (*PaymentsClient, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key is missing")
+	}
+	baseurl, err := url.Parse(DefaultPaymentsURL)
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentsClient{apiKey: apiKey, baseurl: baseurl, httpClient: newDefaultClient()}, nil
+}
+
+/*
+SetHTTPClient sets the http.Client that is used for all requests. When client
+is nil, a client with the transport of NewTransport is used, like Mollie does.
+*/
+func (client *PaymentsClient) SetHTTPClient(httpClient *http.Client) {
+	if httpClient == nil {
+		httpClient = newDefaultClient()
+	}
+	client.httpClient = httpClient
+}
+
+/*
+SetBaseURL replaces the URL of the payments endpoint, for example to use a
+mock server in tests.
+*/
+func (client *PaymentsClient) SetBaseURL(rawurl string) error {
+	baseurl, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	if !baseurl.IsAbs() || baseurl.Host == "" {
+		return fmt.Errorf("base URL %q is not an absolute URL", rawurl)
+	}
+	client.baseurl = baseurl
+	return nil
+}
+
+/*
+CreatePayment creates a new payment. Redirect the consumer to the CheckoutURL
+of the returned payment.
+*/
+func (client *PaymentsClient) CreatePayment(ctx context.Context, request *CreatePaymentRequest) (*Payment, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	res := Payment{}
+	if err := client.do(ctx, "POST", *client.baseurl, body, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+/*
+GetPayment returns the payment with the given id.
+*/
+func (client *PaymentsClient) GetPayment(ctx context.Context, id string) (*Payment, error) {
+	u := *client.baseurl
+	// RawPath keeps a slash in id from becoming a path separator.
+	u.RawPath = u.EscapedPath() + "/" + url.PathEscape(id)
+	u.Path += "/" + id
+	res := Payment{}
+	if err := client.do(ctx, "GET", u, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+/*
+ListPayments returns the most recent payments.
+*/
+func (client *PaymentsClient) ListPayments(ctx context.Context) ([]Payment, error) {
+	var res struct {
+		Embedded struct {
+			Payments []Payment `json:"payments"`
+		} `json:"_embedded"`
+	}
+	if err := client.do(ctx, "GET", *client.baseurl, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Embedded.Payments, nil
+}
+
+/*
+do sends a request with the JSON body to u, and decodes the JSON response
+into v.
+*/
+func (client *PaymentsClient) do(ctx context.Context, method string, u url.URL, body []byte, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+client.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := readAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := PaymentsError{}
+		if err := json.Unmarshal(data, &e); err != nil || e.Status == 0 {
+			return fmt.Errorf("StatusCode not 2xx, but %d", resp.StatusCode)
+		}
+		return &e
+	}
+	return json.Unmarshal(data, v)
+}
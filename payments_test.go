@@ -0,0 +1,175 @@
+package mollie_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+)
+
+const paymentJSON = `{
+	"id": "tr_WDqYK6vllg",
+	"mode": "test",
+	"status": "open",
+	"amount": {"currency": "EUR", "value": "19.99"},
+	"description": "Order 1",
+	"redirectUrl": "https://example.com/return",
+	"_links": {"checkout": {"href": "https://www.mollie.com/payscreen/select-method/WDqYK6vllg", "type": "text/html"}}
+}`
+
+/*
+newPaymentsClient returns a Payments API client that sends its requests to
+handler.
+*/
+func newPaymentsClient(t *testing.T, handler http.HandlerFunc) *mollie.PaymentsClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := mollie.NewPaymentsClient("test_key")
+	if err != nil {
+		t.Fatalf("NewPaymentsClient: %v", err)
+	}
+	if err := client.SetBaseURL(server.URL + "/v2/payments"); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	return client
+}
+
+func TestCreatePayment(t *testing.T) {
+	var got mollie.CreatePaymentRequest
+	client := newPaymentsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v2/payments" {
+			t.Errorf("request = %s %s, want POST /v2/payments", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test_key" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding the request: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, paymentJSON)
+	})
+
+	request := &mollie.CreatePaymentRequest{
+		Amount:      mollie.NewPaymentAmount(1999, "EUR"),
+		Description: "Order 1",
+		RedirectURL: "https://example.com/return",
+	}
+	payment, err := client.CreatePayment(context.Background(), request)
+	if err != nil {
+		t.Fatalf("CreatePayment: %v", err)
+	}
+	if got != *request {
+		t.Errorf("request = %+v, want %+v", got, *request)
+	}
+	if payment.ID != "tr_WDqYK6vllg" || payment.Status != mollie.PaymentOpen {
+		t.Errorf("payment = %+v", payment)
+	}
+	if payment.CheckoutURL() != "https://www.mollie.com/payscreen/select-method/WDqYK6vllg" {
+		t.Errorf("CheckoutURL = %q", payment.CheckoutURL())
+	}
+	if payment.IsPaid() {
+		t.Errorf("IsPaid = true for an open payment")
+	}
+}
+
+func TestGetPayment(t *testing.T) {
+	tests := []struct {
+		id      string
+		rawPath string
+	}{
+		{"tr_WDqYK6vllg", "/v2/payments/tr_WDqYK6vllg"},
+		{"a/b c%", "/v2/payments/a%2Fb%20c%25"},
+	}
+	for _, tt := range tests {
+		var rawPath string
+		client := newPaymentsClient(t, func(w http.ResponseWriter, r *http.Request) {
+			rawPath = r.URL.EscapedPath()
+			fmt.Fprint(w, paymentJSON)
+		})
+		if _, err := client.GetPayment(context.Background(), tt.id); err != nil {
+			t.Fatalf("GetPayment(%q): %v", tt.id, err)
+		}
+		if rawPath != tt.rawPath {
+			t.Errorf("GetPayment(%q) requested %s, want %s", tt.id, rawPath, tt.rawPath)
+		}
+	}
+}
+
+func TestPaymentsError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    *mollie.PaymentsError
+		wantMsg string
+	}{
+		{"payments error", http.StatusNotFound,
+			`{"status": 404, "title": "Not Found", "detail": "No payment exists with token tr_x."}`,
+			&mollie.PaymentsError{Status: 404, Title: "Not Found", Detail: "No payment exists with token tr_x."},
+			"Mollie error 404 Not Found: No payment exists with token tr_x."},
+		{"not json", http.StatusBadGateway, "Bad Gateway", nil, "StatusCode not 2xx, but 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newPaymentsClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			_, err := client.GetPayment(context.Background(), "tr_x")
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("err = %q, want %q", err, tt.wantMsg)
+			}
+			var e *mollie.PaymentsError
+			if errors.As(err, &e) != (tt.want != nil) {
+				t.Fatalf("err = %#v, want %#v", err, tt.want)
+			}
+			if tt.want != nil && *e != *tt.want {
+				t.Errorf("err = %+v, want %+v", *e, *tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPaymentAmount(t *testing.T) {
+	tests := []struct {
+		amount mollie.Amount
+		want   string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{100, "1.00"},
+		{1999, "19.99"},
+		{-1999, "-19.99"},
+		{-5, "-0.05"},
+		{mollie.MaximumAmount, "21474836.47"},
+	}
+	for _, tt := range tests {
+		got := mollie.NewPaymentAmount(tt.amount, "EUR")
+		if got.Value != tt.want || got.Currency != "EUR" {
+			t.Errorf("NewPaymentAmount(%d) = %+v, want %s EUR", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestPaymentsBodyTooLarge(t *testing.T) {
+	client := newPaymentsClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "`+strings.Repeat("x", mollie.DefaultMaxBodySize)+`"}`)
+	})
+	if _, err := client.GetPayment(context.Background(), "tr_x"); !errors.Is(err, mollie.ErrBodyTooLarge) {
+		t.Errorf("err = %v, want ErrBodyTooLarge", err)
+	}
+}
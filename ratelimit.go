@@ -0,0 +1,87 @@
+/*
+ratelimit.go - limit the rate of requests to the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+limiter is a token bucket. Tokens are added at rate per second, up to burst
+tokens.
+*/
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+/*
+wait takes a token from the bucket, and waits until it is available. When ctx
+is done before that, the token is returned to the bucket and ctx.Err() is
+returned.
+*/
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, time.Duration(deficit/l.rate*float64(time.Second))); err != nil {
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+/*
+SetRateLimit limits the number of requests to Mollie to rps per second, with
+bursts of at most burst requests. Requests wait until they are allowed, or
+until their context is done. A zero rate means unlimited.
+*/
+func (mollie *Mollie) SetRateLimit(rps float64, burst int) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	if rps <= 0 {
+		mollie.limiter = nil
+		return
+	}
+	mollie.limiter = newLimiter(rps, burst)
+}
@@ -0,0 +1,76 @@
+package mollie
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurst(t *testing.T) {
+	l := newLimiter(10, 3)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait %d: %v", i, err)
+		}
+	}
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Errorf("burst of 3 took %s, want no wait", d)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l := newLimiter(20, 1)
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	// The bucket is empty, so the next token comes after 1/20 second.
+	start := time.Now()
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if d := time.Since(start); d < 40*time.Millisecond || d > 150*time.Millisecond {
+		t.Errorf("wait took %s, want about 50ms", d)
+	}
+
+	// After a pause the bucket is full again, but never fuller than burst.
+	time.Sleep(200 * time.Millisecond)
+	start = time.Now()
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Errorf("wait after a pause took %s, want no wait", d)
+	}
+	start = time.Now()
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("second wait after a pause took %s, want about 50ms", d)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := newLimiter(1, 1)
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if err := l.wait(ctx); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Errorf("wait returned after %s, want right after the cancel", d)
+	}
+
+	// The token of the cancelled wait was returned, so only one is owed.
+	l.mu.Lock()
+	tokens := l.tokens
+	l.mu.Unlock()
+	if tokens < -0.1 {
+		t.Errorf("tokens = %f after the cancelled wait, want about 0", tokens)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+report.go - handle calls of Mollie to the report URL
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+/*
+VerifyReport extracts the transaction id from a call of Mollie to your report
+URL. It only checks that the id looks like a transaction id.
+
+The iDEAL API doesn't sign these calls, so anybody who knows the report URL
+can call it. Never trust the call itself: the authoritative status of the
+transaction is always the result of Check. CheckReport does both steps.
+*/
+func VerifyReport(r *http.Request) (string, error) {
+	return transactionIdFrom(r, "report", DefaultReturnParam)
+}
+
+/*
+transactionIdFrom returns the transaction id in the parameter param of r.
+what describes the request in errors.
+*/
+func transactionIdFrom(r *http.Request, what, param string) (string, error) {
+	if err := r.ParseForm(); err != nil {
+		return "", err
+	}
+	ids := r.Form[param]
+	if len(ids) == 0 {
+		return "", fmt.Errorf("%s contains no %s parameter", what, param)
+	}
+	if len(ids) != 1 {
+		return "", fmt.Errorf("%s contains %d transaction ids, but expected 1", what, len(ids))
+	}
+	id := ids[0]
+	if !isTransactionId(id) {
+		return "", fmt.Errorf("%s contains an invalid transaction id", what)
+	}
+	return id, nil
+}
+
+/*
+isTransactionId returns true when id only contains letters and digits and has
+a sensible length.
+*/
+func isTransactionId(id string) bool {
+	if len(id) == 0 || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+CheckReport handles a call of Mollie to your report URL. It extracts the
+transaction id with VerifyReport and calls Check to get the status of the
+transaction from Mollie.
+*/
+func (mollie *Mollie) CheckReport(ctx context.Context, r *http.Request) (*MollieResponse, error) {
+	id, err := VerifyReport(r)
+	if err != nil {
+		return nil, err
+	}
+	return mollie.CheckContext(ctx, id)
+}
+
+/*
+DefaultReturnParam is the parameter that contains the transaction id when
+Mollie calls the report URL or redirects the consumer to the return URL.
+*/
+const DefaultReturnParam = "transaction_id"
+
+/*
+SetReturnParam sets the parameter of the return URL that HandleReturn reads
+the transaction id from. When name is empty, DefaultReturnParam is used.
+*/
+func (mollie *Mollie) SetReturnParam(name string) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.returnParam = name
+}
+
+/*
+HandleReturn handles the request of a consumer that Mollie redirected to your
+return URL after the payment. It extracts the transaction id and calls Check
+with the context of r, so the returned status comes from Mollie and not from
+the request.
+*/
+func (mollie *Mollie) HandleReturn(r *http.Request) (*MollieResponse, error) {
+	mollie.mu.RLock()
+	param := mollie.returnParam
+	mollie.mu.RUnlock()
+	if param == "" {
+		param = DefaultReturnParam
+	}
+	id, err := transactionIdFrom(r, "return URL", param)
+	if err != nil {
+		return nil, err
+	}
+	return mollie.CheckContext(r.Context(), id)
+}
@@ -0,0 +1,62 @@
+package mollie_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestCheckReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		warn    bool
+		wantErr error
+	}{
+		{"valid", "transaction_id=" + mollietest.TransactionId, false, nil},
+		{"mismatch", "transaction_id=abc123", false, mollie.ErrTransactionMismatch},
+		{"mismatch warn only", "transaction_id=abc123", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mollietest.NewServer()
+			defer s.Close()
+			s.SetResponse("check", http.StatusOK, mollietest.CheckXML)
+			s.Mollie.SetWarnOnTransactionMismatch(tt.warn)
+
+			r := httptest.NewRequest("GET", "/report?"+tt.query, nil)
+			_, err := s.Mollie.CheckReport(context.Background(), r)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("CheckReport: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyReport(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"transaction_id=abc123", "abc123", false},
+		{"", "", true},
+		{"transaction_id=a&transaction_id=b", "", true},
+		{"transaction_id=not+valid", "", true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/report?"+tt.query, nil)
+		id, err := mollie.VerifyReport(r)
+		if (err != nil) != tt.wantErr || id != tt.want {
+			t.Errorf("VerifyReport(%q) = %q, %v", tt.query, id, err)
+		}
+	}
+}
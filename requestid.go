@@ -0,0 +1,123 @@
+/*
+requestid.go - ids that correlate requests to the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+/*
+ClientRequestIDHeader is the HTTP header that contains the id that this
+package assigned to a request. It is different from RequestIDHeader, which
+contains the id that Mollie assigned.
+*/
+const ClientRequestIDHeader = "X-Client-Request-Id"
+
+/*
+NewRequestID returns a random UUID (version 4). It is the default function
+that generates the request ids.
+*/
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+/*
+SetRequestIDFunc sets the function that generates the id of every BankList,
+Fetch and Check. The id is sent in the ClientRequestIDHeader, logged with
+every request and included in the returned errors. When f is nil,
+NewRequestID is used.
+*/
+func (mollie *Mollie) SetRequestIDFunc(f func() string) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.requestIDFunc = f
+}
+
+type requestIDKey struct{}
+
+/*
+WithRequestID returns a context that makes the BankList, Fetch or Check that
+uses it use id as request id, instead of generating one.
+*/
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+/*
+requestIDFrom returns the request id in ctx, or an empty string.
+*/
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+/*
+withRequestID returns ctx with a request id for an operation, and the id. An
+id that was already set with WithRequestID is kept.
+*/
+func (mollie *Mollie) withRequestID(ctx context.Context) (context.Context, string) {
+	if id := requestIDFrom(ctx); id != "" {
+		return ctx, id
+	}
+	mollie.mu.RLock()
+	f := mollie.requestIDFunc
+	mollie.mu.RUnlock()
+	if f == nil {
+		f = NewRequestID
+	}
+	id := f()
+	return WithRequestID(ctx, id), id
+}
+
+/*
+RequestIDError is returned by BankList, Fetch and Check when they fail,
+unless their context was done. It contains the id of the request, so the
+failure can be found in the logs.
+*/
+type RequestIDError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestIDError) Error() string {
+	return fmt.Sprintf("request %s: %v", e.RequestID, e.Err)
+}
+
+func (e *RequestIDError) Unwrap() error {
+	return e.Err
+}
+
+/*
+wrapRequestID returns err as a RequestIDError with id. It returns nil if err
+is nil. context.Canceled and context.DeadlineExceeded are returned as they
+are, so a cancelled call returns the error of its context.
+*/
+func wrapRequestID(id string, err error) error {
+	if err == nil || err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	return &RequestIDError{RequestID: id, Err: err}
+}
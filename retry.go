@@ -0,0 +1,107 @@
+/*
+retry.go - retry failed requests to the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+/*
+SetRetryPolicy enables retrying of requests that fail with a network error or
+a 5xx status code. A request is tried at most maxRetries+1 times. Before retry
+n (starting at 0) the client waits baseDelay*2^n, with random jitter. A
+maxRetries of 0 disables retrying.
+
+Fetch is retried too. When Mollie received a request that failed, the retry
+can create a second transaction.
+*/
+func (mollie *Mollie) SetRetryPolicy(maxRetries int, baseDelay time.Duration) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.maxRetries = maxRetries
+	mollie.retryDelay = baseDelay
+}
+
+/*
+SetRetryPredicate sets the function that decides whether a failed request is
+tried again, instead of the default rule of retrying network errors and 5xx
+status codes. It is called after each attempt with either the response or the
+error, and is only consulted while retries are left according to
+SetRetryPolicy. The predicate must not close the body of resp. A nil predicate
+restores the default rule.
+*/
+func (mollie *Mollie) SetRetryPredicate(predicate func(resp *http.Response, err error) bool) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.retryIf = predicate
+}
+
+/*
+shouldRetry reports whether a request that resulted in resp and err should be
+tried again.
+*/
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500
+}
+
+/*
+maxBackoff is the maximum delay before a retry.
+*/
+const maxBackoff = time.Hour
+
+/*
+backoff returns the delay before retry n. The delay is between half and the
+full value of baseDelay*2^n, which is at most maxBackoff.
+*/
+func backoff(baseDelay time.Duration, n int) time.Duration {
+	if baseDelay <= 0 {
+		return 0
+	}
+	d := maxBackoff
+	if n < 63 && baseDelay <= maxBackoff>>uint(n) {
+		d = baseDelay << uint(n)
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+/*
+sleep waits for d or until ctx is done, whichever comes first. It returns
+ctx.Err() when ctx was done. When the deadline of ctx is earlier than the end
+of the sleep, it returns context.DeadlineExceeded right away, because the
+caller can't do anything useful after the sleep.
+*/
+func sleep(ctx context.Context, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return context.DeadlineExceeded
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
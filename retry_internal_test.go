@@ -0,0 +1,31 @@
+package mollie
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		baseDelay time.Duration
+		n         int
+		min, max  time.Duration
+	}{
+		{0, 3, 0, 0},
+		{100 * time.Millisecond, 0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{100 * time.Millisecond, 3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{time.Second, 40, maxBackoff / 2, maxBackoff},
+		{time.Second, 63, maxBackoff / 2, maxBackoff},
+		{time.Second, 1000, maxBackoff / 2, maxBackoff},
+		{maxBackoff * 2, 0, maxBackoff / 2, maxBackoff},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ {
+			d := backoff(tt.baseDelay, tt.n)
+			if d < tt.min || d > tt.max {
+				t.Errorf("backoff(%s, %d) = %s, want between %s and %s", tt.baseDelay, tt.n, d, tt.min, tt.max)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package mollie_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+/*
+newMollie returns a client that sends its requests to handler.
+*/
+func newMollie(t *testing.T, handler http.HandlerFunc) *mollie.Mollie {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	m, err := mollie.NewMollieWithOptions(mollietest.PartnerId, mollie.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestRetryAfterAttemptTimeout(t *testing.T) {
+	var attempts int32
+	m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		fmt.Fprint(w, mollietest.CheckXML)
+	})
+	m.SetTimeout(50 * time.Millisecond)
+	m.SetRetryPolicy(1, time.Millisecond)
+
+	resp, err := m.Check(mollietest.TransactionId)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if !resp.IsSuccess() {
+		t.Errorf("status = %s, want %s", resp.Status(), mollie.StatusSuccess)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("attempts = %d, want 2", n)
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	var attempts int32
+	m := newMollie(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	})
+	m.SetRetryPolicy(5, 2*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := m.CheckContext(ctx, mollietest.TransactionId)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Errorf("Check returned after %s, want right away", d)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+stream.go - stream the bank list of the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+/*
+BankListEach requests the bank list from Mollie, like BankListContext, and
+calls fn with every bank while the response is decoded, so the whole list
+isn't kept in memory. When fn returns an error, BankListEach stops and returns
+it. BankListEach doesn't use the bank list cache or the fallback, and returns
+ErrNoBanksAvailable when Mollie returned no banks.
+
+With SetStrictDecoding, the whole response is read and checked like BankList
+does before fn is called, so fn never sees the banks of a rejected response.
+*/
+func (mollie *Mollie) BankListEach(ctx context.Context, fn func(Bank) error) error {
+	ctx, id := mollie.withRequestID(ctx)
+	ctx, finish := mollie.trace(ctx, "banklist")
+	err := mollie.bankListEach(ctx, fn)
+	err = wrapRequestID(id, err)
+	finish(err)
+	return err
+}
+
+func (mollie *Mollie) bankListEach(ctx context.Context, fn func(Bank) error) error {
+	resp, err := mollie.get(ctx, "banklist", mollie.BankListURL())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return err
+	}
+
+	mollie.mu.RLock()
+	limit := mollie.maxBody
+	mollie.mu.RUnlock()
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	opts := mollie.decodeOptions()
+	maxDepth := opts.maxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxXMLDepth
+	}
+
+	body := &bodyLimiter{r: resp.Body, n: limit}
+	if opts.strict {
+		data, err := mollie.readBody(resp)
+		if err != nil {
+			return err
+		}
+		if item := errorItem(data); item != nil {
+			return item
+		}
+		if err := checkElements(data, &BankResponse{}); err != nil {
+			return wrap(ErrDecodeFailed, err)
+		}
+		body = &bodyLimiter{r: bytes.NewReader(data), n: limit}
+	}
+
+	// fail returns the error of the body when reading it failed, because
+	// that isn't an XML error.
+	fail := func(err error) error {
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case resp.Request != nil && resp.Request.Context().Err() != nil:
+			return resp.Request.Context().Err()
+		case body.err == ErrBodyTooLarge:
+			return fmt.Errorf("response is larger than %d bytes: %w", limit, ErrBodyTooLarge)
+		case body.err != nil:
+			return wrap(ErrTransport, body.err)
+		}
+		return wrap(ErrDecodeFailed, err)
+	}
+
+	d := xml.NewDecoder(body)
+	d.CharsetReader = charsetReader
+	depth := 0
+	n := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth > maxDepth {
+				return wrap(ErrDecodeFailed, fmt.Errorf("response is nested deeper than %d elements", maxDepth))
+			}
+			switch {
+			case tok.Name.Local == "bank":
+				var bank Bank
+				if err := d.DecodeElement(&bank, &tok); err != nil {
+					return fail(err)
+				}
+				depth--
+				n++
+				if err := fn(bank); err != nil {
+					return err
+				}
+			case tok.Name.Local == "item" && attr(tok, "type") == "error":
+				var mollieErr MollieError
+				if err := d.DecodeElement(&mollieErr, &tok); err != nil {
+					return fail(err)
+				}
+				return &mollieErr
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if n == 0 {
+		return ErrNoBanksAvailable
+	}
+	return nil
+}
+
+/*
+attr returns the value of the attribute name of start.
+*/
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+/*
+bodyLimiter reads at most n bytes from r, and returns ErrBodyTooLarge when r
+contains more. err is the first error other than io.EOF that it returned.
+*/
+type bodyLimiter struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (l *bodyLimiter) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		err = ErrBodyTooLarge
+	}
+	if err != nil && err != io.EOF && l.err == nil {
+		l.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,126 @@
+package mollie_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestBankListEach(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+
+	var ids []int
+	err := s.Mollie.BankListEach(context.Background(), func(bank mollie.Bank) error {
+		ids = append(ids, bank.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("BankListEach: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != 31 || ids[1] != 721 || ids[2] != 21 {
+		t.Errorf("banks = %v, want [31 721 21]", ids)
+	}
+}
+
+func TestBankListEachStop(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+
+	stop := errors.New("stop")
+	calls := 0
+	err := s.Mollie.BankListEach(context.Background(), func(bank mollie.Bank) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("err = %v, want the error of fn", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestBankListEachErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		strict  bool
+		maxBody int64
+		check   func(err error) bool
+	}{
+		{"error item", `<?xml version="1.0"?>
+<response>
+	<item type="error">
+		<errorcode>-16</errorcode>
+		<message>This is an invalid profile key.</message>
+	</item>
+</response>`, false, 0, func(err error) bool { return errors.Is(err, mollie.ErrInvalidProfile) }},
+		{"error item strict", `<?xml version="1.0"?>
+<response>
+	<item type="error">
+		<errorcode>-16</errorcode>
+		<message>This is an invalid profile key.</message>
+	</item>
+</response>`, true, 0, func(err error) bool { return errors.Is(err, mollie.ErrInvalidProfile) }},
+		{"no banks", `<?xml version="1.0"?><response></response>`, false, 0,
+			func(err error) bool { return errors.Is(err, mollie.ErrNoBanksAvailable) }},
+		{"too large", mollietest.BankListXML, false, 100, func(err error) bool {
+			return errors.Is(err, mollie.ErrBodyTooLarge) && !errors.Is(err, mollie.ErrDecodeFailed)
+		}},
+		{"too large strict", mollietest.BankListXML, true, 100, func(err error) bool {
+			return errors.Is(err, mollie.ErrBodyTooLarge) && !errors.Is(err, mollie.ErrDecodeFailed)
+		}},
+		{"syntax error", `<response><bank>`, false, 0,
+			func(err error) bool { return errors.Is(err, mollie.ErrDecodeFailed) }},
+		{"unknown element strict", strings.Replace(mollietest.BankListXML, "</response>", "<extra>1</extra></response>", 1), true, 0,
+			func(err error) bool { return errors.Is(err, mollie.ErrDecodeFailed) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := mollietest.NewServer()
+			defer s.Close()
+			s.SetResponse("banklist", http.StatusOK, tt.body)
+			s.Mollie.SetStrictDecoding(tt.strict)
+			s.Mollie.SetMaxBodySize(tt.maxBody)
+
+			calls := 0
+			err := s.Mollie.BankListEach(context.Background(), func(bank mollie.Bank) error {
+				calls++
+				return nil
+			})
+			if !tt.check(err) {
+				t.Errorf("err = %v", err)
+			}
+			// BankList returns the same kind of error.
+			if _, err := s.Mollie.BankList(); !tt.check(err) {
+				t.Errorf("BankList: err = %v", err)
+			}
+			if tt.strict && calls != 0 {
+				t.Errorf("calls = %d, want 0 for a rejected response", calls)
+			}
+		})
+	}
+}
+
+func TestBankListEachUnknownElement(t *testing.T) {
+	body := strings.Replace(mollietest.BankListXML, "</response>", "<extra>1</extra></response>", 1)
+	s := mollietest.NewServer()
+	defer s.Close()
+	s.SetResponse("banklist", http.StatusOK, body)
+
+	// Unknown elements are ignored without strict decoding.
+	calls := 0
+	err := s.Mollie.BankListEach(context.Background(), func(bank mollie.Bank) error {
+		calls++
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("err = %v, calls = %d, want 3 banks", err, calls)
+	}
+}
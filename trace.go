@@ -0,0 +1,86 @@
+/*
+trace.go - trace requests to the Mollie iDEAL API
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"time"
+)
+
+/*
+TraceFunc is called at the start of every BankList, Fetch and Check with the
+name of the operation: "banklist", "fetch" or "check". It returns the context
+for the operation, for example with a new span, and a function that is called
+with the result of the operation when it is finished.
+*/
+type TraceFunc func(ctx context.Context, op string) (context.Context, func(err error))
+
+/*
+SetTracer sets the function that traces operations. When tracer is nil,
+nothing is traced.
+*/
+func (mollie *Mollie) SetTracer(tracer TraceFunc) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.tracer = tracer
+}
+
+/*
+MetricsHook is called at the end of every BankList, Fetch and Check with the
+name of the operation, its duration and its error, which is nil on success.
+*/
+type MetricsHook func(op string, duration time.Duration, err error)
+
+/*
+SetMetricsHook sets the function that receives the duration of operations.
+When hook is nil, nothing is measured.
+*/
+func (mollie *Mollie) SetMetricsHook(hook MetricsHook) {
+	mollie.mu.Lock()
+	defer mollie.mu.Unlock()
+	mollie.metricsHook = hook
+}
+
+/*
+trace starts the operation op for the tracer and the metrics hook. The
+returned function must be called with the result of the operation.
+*/
+func (mollie *Mollie) trace(ctx context.Context, op string) (context.Context, func(error)) {
+	mollie.mu.RLock()
+	tracer := mollie.tracer
+	hook := mollie.metricsHook
+	mollie.mu.RUnlock()
+
+	finishTrace := func(error) {}
+	if tracer != nil {
+		traceCtx, finish := tracer(ctx, op)
+		if traceCtx != nil {
+			ctx = traceCtx
+		}
+		if finish != nil {
+			finishTrace = finish
+		}
+	}
+	start := time.Now()
+	return ctx, func(err error) {
+		if hook != nil {
+			hook(op, time.Since(start), err)
+		}
+		finishTrace(err)
+	}
+}
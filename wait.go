@@ -0,0 +1,98 @@
+/*
+wait.go - wait for a Mollie iDEAL payment to complete
+Copyright (c) 2013 Peter Stuifzand
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Lesser General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Lesser General Public License for more details.
+
+You should have received a copy of the GNU Lesser General Public License
+along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+package mollie
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+/*
+DefaultWaitJitter is the default jitter of WaitForPayment: every interval is
+changed by up to 10% at random.
+*/
+const DefaultWaitJitter = 0.1
+
+/*
+WaitOption changes how WaitForPayment polls.
+*/
+type WaitOption func(w *waitOptions)
+
+type waitOptions struct {
+	jitter float64
+}
+
+/*
+WaitWithJitter changes every polling interval of WaitForPayment by a random
+amount of up to fraction of the interval, so 0.2 spreads the intervals by
+±20%. This keeps many clients that started at the same moment from polling
+Mollie at the same moment. A fraction of 0 disables the jitter.
+*/
+func WaitWithJitter(fraction float64) WaitOption {
+	return func(w *waitOptions) {
+		w.jitter = fraction
+	}
+}
+
+/*
+jitter returns d changed by a random amount of up to fraction of d.
+*/
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	return d + time.Duration((rand.Float64()*2-1)*fraction*float64(d))
+}
+
+/*
+WaitForPayment calls Check every interval until the transaction is
+completed, and returns the last response. A transaction is completed when its
+status is Success, Failure, Expired or Cancelled. It also stops on
+CheckedBefore, because that status means the transaction was completed and
+checked earlier.
+
+Every interval is changed at random by up to DefaultWaitJitter, unless
+WaitWithJitter is passed. WaitForPayment returns an error when interval isn't
+positive, when a Check fails or when ctx is done.
+*/
+func (mollie *Mollie) WaitForPayment(ctx context.Context, transactionId string, interval time.Duration, opts ...WaitOption) (*MollieResponse, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, but is %s", interval)
+	}
+	w := waitOptions{jitter: DefaultWaitJitter}
+	for _, opt := range opts {
+		opt(&w)
+	}
+	for {
+		resp, err := mollie.CheckContext(ctx, transactionId)
+		if err != nil {
+			return nil, err
+		}
+		if resp.IsTerminal() || resp.IsCheckedBefore() {
+			return resp, nil
+		}
+		if err := sleep(ctx, jitter(interval, w.jitter)); err != nil {
+			return nil, err
+		}
+	}
+}
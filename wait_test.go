@@ -0,0 +1,45 @@
+package mollie_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pstuifzand/go-mollie"
+	"github.com/pstuifzand/go-mollie/mollietest"
+)
+
+func TestWaitForPaymentInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		s := mollietest.NewServer()
+		_, err := s.Mollie.WaitForPayment(context.Background(), mollietest.TransactionId, interval)
+		if err == nil {
+			t.Errorf("interval %s: no error", interval)
+		}
+		if n := len(s.Requests()); n != 0 {
+			t.Errorf("interval %s: requests = %d, want 0", interval, n)
+		}
+		s.Close()
+	}
+}
+
+func TestWaitForPayment(t *testing.T) {
+	s := mollietest.NewServer()
+	defer s.Close()
+	open := strings.Replace(mollietest.CheckXML, "<status>Success</status>", "<status>Open</status>", 1)
+	s.SetNextResponse("check", http.StatusOK, open)
+	s.SetNextResponse("check", http.StatusOK, open)
+
+	resp, err := s.Mollie.WaitForPayment(context.Background(), mollietest.TransactionId, time.Millisecond, mollie.WaitWithJitter(0))
+	if err != nil {
+		t.Fatalf("WaitForPayment: %v", err)
+	}
+	if !resp.IsTerminal() {
+		t.Errorf("status = %s, want a completed transaction", resp.Order.Status)
+	}
+	if n := len(s.Requests()); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+}